@@ -0,0 +1,197 @@
+package goblin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// appendedVaultMagic identifies the trailer written after a vault appended to a file.
+var appendedVaultMagic = []byte("GOBLINAV")
+
+// appendedVaultTrailerSize is the size of the trailer: the offset and length of the
+// vault data as big-endian uint64s, the SHA-256 checksum of the data, then the magic.
+const appendedVaultTrailerSize = 8 + 8 + sha256.Size + 8
+
+// ErrNoAppendedVault is returned when a file doesn't have a vault appended to it.
+var ErrNoAppendedVault = errors.New("no appended vault found")
+
+type appendedVaultTrailer struct {
+	offset   int64
+	length   int64
+	checksum []byte
+}
+
+func (t appendedVaultTrailer) MarshalBinary() ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, appendedVaultTrailerSize))
+
+	err := binary.Write(buf, binary.BigEndian, uint64(t.offset))
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(buf, binary.BigEndian, uint64(t.length))
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(t.checksum)
+	buf.Write(appendedVaultMagic)
+
+	return buf.Bytes(), nil
+}
+
+func (t *appendedVaultTrailer) UnmarshalBinary(data []byte) error {
+	if len(data) != appendedVaultTrailerSize ||
+		!bytes.Equal(data[appendedVaultTrailerSize-len(appendedVaultMagic):], appendedVaultMagic) {
+		return ErrNoAppendedVault
+	}
+
+	t.offset = int64(binary.BigEndian.Uint64(data[0:8]))
+	t.length = int64(binary.BigEndian.Uint64(data[8:16]))
+	t.checksum = append([]byte(nil), data[16:16+sha256.Size]...)
+
+	return nil
+}
+
+// readAppendedVaultTrailer reads the trailer from the end of the file. If the file
+// doesn't end with a valid trailer, ErrNoAppendedVault is returned.
+func readAppendedVaultTrailer(f *os.File) (*appendedVaultTrailer, error) {
+	fInfo, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fInfo.Size() < appendedVaultTrailerSize {
+		return nil, ErrNoAppendedVault
+	}
+
+	trailerData := make([]byte, appendedVaultTrailerSize)
+	_, err = f.ReadAt(trailerData, fInfo.Size()-appendedVaultTrailerSize)
+	if err != nil {
+		return nil, err
+	}
+
+	trailer := &appendedVaultTrailer{}
+	err = trailer.UnmarshalBinary(trailerData)
+	if err != nil {
+		return nil, err
+	}
+
+	if trailer.offset < 0 || trailer.length < 0 ||
+		trailer.offset+trailer.length+appendedVaultTrailerSize != fInfo.Size() {
+		return nil, fmt.Errorf("appended vault trailer is corrupt")
+	}
+
+	return trailer, nil
+}
+
+// AppendVaultData appends the binary representation of a vault, such as one written
+// by MemoryBuilder.WriteBinary, to the end of the file at the provided path followed
+// by a trailer used to locate it. Executables still run normally with a vault
+// appended. If the file already has an appended vault, it's replaced.
+func AppendVaultData(filePath string, vaultData []byte) error {
+	f, err := os.OpenFile(filePath, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fInfo, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	offset := fInfo.Size()
+
+	trailer, err := readAppendedVaultTrailer(f)
+	if err == nil {
+		offset = trailer.offset
+	} else if err != ErrNoAppendedVault {
+		return err
+	}
+
+	err = f.Truncate(offset)
+	if err != nil {
+		return err
+	}
+
+	checksum := sha256.Sum256(vaultData)
+	trailerData, err := appendedVaultTrailer{
+		offset:   offset,
+		length:   int64(len(vaultData)),
+		checksum: checksum[:],
+	}.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	_, err = f.WriteAt(vaultData, offset)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteAt(trailerData, offset+int64(len(vaultData)))
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// ReadAppendedVaultData reads the binary representation of the vault appended to the
+// file at the provided path. If the file doesn't have an appended vault,
+// ErrNoAppendedVault is returned.
+func ReadAppendedVaultData(filePath string) ([]byte, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	trailer, err := readAppendedVaultTrailer(f)
+	if err != nil {
+		return nil, err
+	}
+
+	vaultData := make([]byte, trailer.length)
+	_, err = f.ReadAt(vaultData, trailer.offset)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	checksum := sha256.Sum256(vaultData)
+	if !bytes.Equal(checksum[:], trailer.checksum) {
+		return nil, fmt.Errorf("appended vault checksum does not match")
+	}
+
+	return vaultData, nil
+}
+
+// LoadAppendedVaultFile loads the vault appended to the file at the provided path. If
+// the file doesn't have an appended vault and fallback isn't nil, the vault returned
+// by fallback is used instead, such as a vault loaded by generated code. Otherwise
+// ErrNoAppendedVault is returned.
+func LoadAppendedVaultFile(
+	filePath string, fallback func() (Vault, error), opts ...LoadMemoryOption,
+) (Vault, error) {
+	vaultData, err := ReadAppendedVaultData(filePath)
+	if err == ErrNoAppendedVault && fallback != nil {
+		return fallback()
+	} else if err != nil {
+		return nil, err
+	}
+
+	return LoadMemoryVault(vaultData, opts...)
+}
+
+// LoadAppendedVault loads the vault appended to the running executable, such as one
+// appended with `goblin append`. If the executable doesn't have an appended vault and
+// fallback isn't nil, the vault returned by fallback is used instead.
+func LoadAppendedVault(fallback func() (Vault, error), opts ...LoadMemoryOption) (Vault, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadAppendedVaultFile(exePath, fallback, opts...)
+}
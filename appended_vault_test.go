@@ -0,0 +1,166 @@
+package goblin
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestExecutable(t *testing.T) string {
+	t.Helper()
+
+	tf, err := ioutil.TempFile("", testTempPattern)
+	require.NoError(t, err)
+	defer tf.Close()
+
+	_, err = tf.Write([]byte("\x7fELF not really an executable"))
+	require.NoError(t, err)
+
+	return tf.Name()
+}
+
+func marshalTestVault(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	v := NewMemoryVault()
+	for name, data := range files {
+		require.NoError(t, v.WriteFile(name, bytes.NewBufferString(data)))
+	}
+
+	vaultData, err := v.MarshalBinary()
+	require.NoError(t, err)
+
+	return vaultData
+}
+
+func TestAppendedVault(t *testing.T) {
+	t.Run("append and load", func(t *testing.T) {
+		exePath := writeTestExecutable(t)
+		defer os.Remove(exePath)
+
+		vaultData := marshalTestVault(t, map[string]string{"index.html": "<html></html>"})
+		require.NoError(t, AppendVaultData(exePath, vaultData))
+
+		exeData, err := ioutil.ReadFile(exePath)
+		require.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(exeData, []byte("\x7fELF not really an executable")))
+
+		readData, err := ReadAppendedVaultData(exePath)
+		require.NoError(t, err)
+		assert.Equal(t, vaultData, readData)
+
+		v, err := LoadAppendedVaultFile(exePath, nil)
+		require.NoError(t, err)
+		data, err := v.ReadFile("index.html")
+		require.NoError(t, err)
+		assert.Equal(t, "<html></html>", string(data))
+	})
+
+	t.Run("replace appended vault", func(t *testing.T) {
+		exePath := writeTestExecutable(t)
+		defer os.Remove(exePath)
+
+		origInfo, err := os.Stat(exePath)
+		require.NoError(t, err)
+
+		require.NoError(t, AppendVaultData(exePath, marshalTestVault(t, map[string]string{"old.txt": "old"})))
+		newData := marshalTestVault(t, map[string]string{"new.txt": "new"})
+		require.NoError(t, AppendVaultData(exePath, newData))
+
+		exeInfo, err := os.Stat(exePath)
+		require.NoError(t, err)
+		assert.Equal(t, origInfo.Size()+int64(len(newData))+appendedVaultTrailerSize, exeInfo.Size())
+
+		v, err := LoadAppendedVaultFile(exePath, nil)
+		require.NoError(t, err)
+		_, err = v.Stat("old.txt")
+		assert.True(t, os.IsNotExist(err))
+		_, err = v.Stat("new.txt")
+		assert.NoError(t, err)
+	})
+
+	t.Run("no appended vault", func(t *testing.T) {
+		exePath := writeTestExecutable(t)
+		defer os.Remove(exePath)
+
+		_, err := LoadAppendedVaultFile(exePath, nil)
+		assert.Equal(t, ErrNoAppendedVault, err)
+
+		fallback := NewMemoryVault()
+		v, err := LoadAppendedVaultFile(exePath, func() (Vault, error) {
+			return fallback, nil
+		})
+		require.NoError(t, err)
+		assert.Same(t, fallback, v)
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		exePath := writeTestExecutable(t)
+		defer os.Remove(exePath)
+
+		vaultData := marshalTestVault(t, map[string]string{"index.html": "<html></html>"})
+		require.NoError(t, AppendVaultData(exePath, vaultData))
+
+		f, err := os.OpenFile(exePath, os.O_RDWR, 0)
+		require.NoError(t, err)
+		fInfo, err := f.Stat()
+		require.NoError(t, err)
+		corruptAt := fInfo.Size() - appendedVaultTrailerSize - 1
+		b := []byte{0}
+		_, err = f.ReadAt(b, corruptAt)
+		require.NoError(t, err)
+		_, err = f.WriteAt([]byte{b[0] ^ 0xff}, corruptAt)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		_, err = LoadAppendedVaultFile(exePath, func() (Vault, error) {
+			return NewMemoryVault(), nil
+		})
+		assert.EqualError(t, err, "appended vault checksum does not match")
+	})
+
+	t.Run("corrupt trailer", func(t *testing.T) {
+		exePath := writeTestExecutable(t)
+		defer os.Remove(exePath)
+
+		trailerData, err := appendedVaultTrailer{
+			offset:   1000,
+			length:   10,
+			checksum: make([]byte, 32),
+		}.MarshalBinary()
+		require.NoError(t, err)
+
+		f, err := os.OpenFile(exePath, os.O_APPEND|os.O_WRONLY, 0)
+		require.NoError(t, err)
+		_, err = f.Write(trailerData)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		_, err = ReadAppendedVaultData(exePath)
+		assert.EqualError(t, err, "appended vault trailer is corrupt")
+	})
+}
+
+func TestMemoryBuilderAppendToFile(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	writeTestFiles(t, td, map[string]string{"index.html": "<html></html>"})
+
+	exePath := writeTestExecutable(t)
+	defer os.Remove(exePath)
+
+	b := NewMemoryBuilder()
+	require.NoError(t, b.Include(td, []string{"*"}))
+	require.NoError(t, b.AppendToFile(exePath))
+
+	v, err := LoadAppendedVaultFile(exePath, nil)
+	require.NoError(t, err)
+	_, err = v.Stat("index.html")
+	assert.NoError(t, err)
+}
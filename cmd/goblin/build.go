@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aphistic/goblin"
+	"github.com/aphistic/goblin/internal/manifest"
+)
+
+func runBuild() {
+	m, err := manifest.Load(flagManifest)
+	if err != nil {
+		fatal("Could not load manifest", "error", err)
+	}
+
+	epoch, err := goblin.SourceDateEpoch()
+	if err != nil {
+		fatal("Invalid option", "error", err)
+	}
+
+	// When checking, every vault is checked before exiting so all of the stale
+	// vaults are reported at once.
+	upToDate := true
+	var reports []namedReport
+	for _, v := range m.Vaults {
+		if flagCheck && v.ClampModTime.IsZero() && epoch.IsZero() {
+			fatal("--check requires mtime in the manifest or SOURCE_DATE_EPOCH so modified times are reproducible",
+				"vault", v.Name)
+		}
+
+		b, err := buildManifestVault(v)
+		if err != nil {
+			fatal("Error building vault", "vault", v.Name, "error", err)
+		}
+
+		if flagCheck {
+			vaultUpToDate, err := checkOutput(b, v.Package, v.Name, v.Output, v.Binary, v.Embed)
+			if err != nil {
+				fatal("Error checking vault", "vault", v.Name, "error", err)
+			}
+			upToDate = upToDate && vaultUpToDate
+			continue
+		}
+
+		err = writeOutput(b, v.Package, v.Name, v.Output, v.Binary, v.Embed)
+		if err != nil {
+			fatal("Error building vault", "vault", v.Name, "error", err)
+		}
+
+		if flagReport != "" {
+			report, err := b.Report(flagReportTop)
+			if err != nil {
+				fatal("Error creating size report", "vault", v.Name, "error", err)
+			}
+			reports = append(reports, namedReport{name: v.Name, report: report})
+		}
+	}
+
+	if !upToDate {
+		os.Exit(1)
+	}
+
+	if len(reports) > 0 {
+		err = writeReports(reports, flagReport, flagReportFile)
+		if err != nil {
+			fatal("Error writing size report", "error", err)
+		}
+	}
+}
+
+// buildManifestVault creates a builder for the vault and includes its files.
+func buildManifestVault(v *manifest.Vault) (*goblin.MemoryBuilder, error) {
+	logger.Info("Building vault", "vault", v.Name)
+
+	modTime := v.ClampModTime
+	if modTime.IsZero() {
+		var err error
+		modTime, err = goblin.SourceDateEpoch()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	opts := []goblin.MemoryBuilderOption{
+		goblin.MemoryBuilderLogger(logger),
+		goblin.MemoryBuilderExportLoader(v.ExportLoader),
+		goblin.MemoryBuilderAccessors(v.Accessors),
+		goblin.MemoryBuilderLoaderFormat(parseLoaderFormat(v.LoaderFormat)),
+		goblin.MemoryBuilderCompressionLevel(v.CompressionLevel),
+		goblin.MemoryBuilderClampModTime(modTime),
+		goblin.MemoryBuilderPrecompress(v.Precompress...),
+		goblin.MemoryBuilderFingerprint(v.Fingerprint...),
+		goblin.MemoryBuilderExclude(v.Exclude...),
+		goblin.MemoryBuilderIgnoreFiles(v.IgnoreFiles...),
+		goblin.MemoryBuilderMaxDepth(v.MaxDepth),
+		goblin.MemoryBuilderMaxSize(v.MaxSizeBytes),
+		goblin.MemoryBuilderMaxFileSize(v.MaxFileSizeBytes),
+	}
+	for _, budget := range v.Budgets {
+		opts = append(opts, goblin.MemoryBuilderSizeBudget(budget.Glob, budget.MaxSizeBytes))
+	}
+	for _, transform := range v.Transforms {
+		var transformer goblin.Transformer
+		if len(transform.Exec) > 0 {
+			transformer = goblin.ExecTransformer(transform.Exec[0], transform.Exec[1:]...)
+		} else {
+			var err error
+			transformer, err = goblin.BuiltinTransformer(transform.Transform)
+			if err != nil {
+				return nil, err
+			}
+		}
+		opts = append(opts, goblin.MemoryBuilderTransform(transform.Glob, transformer))
+	}
+
+	b := goblin.NewMemoryBuilder(opts...)
+
+	for _, root := range v.Roots {
+		incOpts := []goblin.IncludeOption{
+			goblin.IncludePrefix(root.Prefix),
+			goblin.IncludeStripSegments(root.StripSegments),
+		}
+		for _, rename := range root.Rename {
+			incOpts = append(incOpts, goblin.IncludeRename(rename.Regexp, rename.Replacement))
+		}
+
+		err := b.Include(root.Path, root.Include, incOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("error including files from %s: %w", root.Path, err)
+		}
+		err = b.IncludeDirs(root.Path, root.IncludeDirs, incOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("error including directories from %s: %w", root.Path, err)
+		}
+	}
+
+	return b, nil
+}
@@ -3,45 +3,454 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/alecthomas/kingpin"
 
 	"github.com/aphistic/goblin"
 	"github.com/aphistic/goblin/internal/logging"
+	"github.com/aphistic/goblin/internal/manifest"
 )
 
+const (
+	execTransformPrefix = "exec:"
+	// stdoutPath is the output path used to write a vault to stdout.
+	stdoutPath = "-"
+
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// logger is used for all of the utility's messages, which are written to stderr so
+// they're kept separate from any vault written to stdout.
+var logger = logging.New(os.Stderr, logging.LevelInfo, logging.FormatText)
+
 var (
-	flagName         string
-	flagPackage      string
-	flagOut          string
-	flagIncludeRoot  string
-	flagIncludes     []string
-	flagExportLoader bool
-	flagBinary       bool
+	flagName          string
+	flagPackage       string
+	flagOut           string
+	flagIncludeRoot   string
+	flagIncludes      []string
+	flagIncludeDirs   []string
+	flagMaxDepth      int
+	flagExportLoader  bool
+	flagBinary        bool
+	flagEmbed         bool
+	flagPrecompress   []string
+	flagFingerprints  []string
+	flagAccessors     bool
+	flagLoaderFormat  string
+	flagMaps          []string
+	flagStripSegments int
+	flagRenames       []string
+	flagExcludes      []string
+	flagIgnoreFiles   []string
+	flagCompression   string
+	flagManifest      string
+	flagModTime       string
+	flagAppendTarget  string
+	flagAppendVault   string
+	flagCheck         bool
+	flagWatch         bool
+	flagWatchInterval time.Duration
+	flagDebounce      time.Duration
+	flagCacheFile     string
+	flagTransforms    []string
+	flagMaxSize       string
+	flagMaxFileSize   string
+	flagBudgets       []string
+	flagReport        string
+	flagReportFile    string
+	flagReportTop     int
+	flagQuiet         bool
+	flagVerbose       bool
+	flagLogFormat     string
 )
 
 func main() {
 	appGoblin := kingpin.New("goblin", "Goblin")
+	appGoblin.Flag("quiet", "Only log warnings and errors").Short('q').BoolVar(&flagQuiet)
+	appGoblin.Flag("verbose", "Also log debug messages, such as excluded files").Short('v').BoolVar(&flagVerbose)
+	appGoblin.Flag("log-format", "Format of log messages written to stderr (text or json)").
+		Default(logFormatText).EnumVar(&flagLogFormat, logFormatText, logFormatJSON)
+
 	cmdCreate := appGoblin.Command("create", "Create a vault").Default()
 	cmdCreate.Flag("name", "Name of the vault to create").Short('n').
 		Required().StringVar(&flagName)
 	cmdCreate.Flag("package", "Name of the package for the output file").Short('p').
 		StringVar(&flagPackage)
-	cmdCreate.Flag("out", "Name to use for the output file").Short('o').
+	cmdCreate.Flag("out", "Name to use for the output file, or - to write to stdout").Short('o').
 		StringVar(&flagOut)
-	cmdCreate.Flag("include-root", "Root path to use when including files in the vault").Short('r').
-		StringVar(&flagIncludeRoot)
-	cmdCreate.Flag("include", "Files to include in the vault").Short('i').
-		StringsVar(&flagIncludes)
+	addBuilderFlags(cmdCreate)
 	cmdCreate.Flag("export-loader", "Export loader in generated code").Short('e').
 		BoolVar(&flagExportLoader)
 	cmdCreate.Flag("binary", "Write out binary data").Short('b').BoolVar(&flagBinary)
+	cmdCreate.Flag("embed", "Write binary data to a .bin file next to the output file and load it with go:embed").
+		BoolVar(&flagEmbed)
+	cmdCreate.Flag("loader-format", "How vault data is stored in generated code (bytes or string)").
+		Default(goblin.LoaderFormatBytes.String()).
+		EnumVar(&flagLoaderFormat, goblin.LoaderFormatBytes.String(), goblin.LoaderFormatString.String())
+	cmdCreate.Flag("accessors", "Generate constants for the path of each included file").
+		BoolVar(&flagAccessors)
+	addReportFlags(cmdCreate)
+	cmdCreate.Flag("check", "Check the output file is up to date instead of writing it").
+		BoolVar(&flagCheck)
+	cmdCreate.Flag("watch", "Rebuild the vault whenever its input files change").Short('w').
+		BoolVar(&flagWatch)
+	cmdCreate.Flag("watch-interval", "How often to check for changes when watching").
+		Default("500ms").DurationVar(&flagWatchInterval)
+	cmdCreate.Flag("debounce", "How long changes must stop for before rebuilding when watching").
+		Default("250ms").DurationVar(&flagDebounce)
+	cmdCreate.Flag("cache-file", "File recording the state of the vault's inputs when watching, defaults to .<out>.cache").
+		StringVar(&flagCacheFile)
+
+	cmdAppend := appGoblin.Command("append", "Append a vault to an existing executable")
+	cmdAppend.Flag("target", "Executable to append the vault to").Short('t').
+		Required().StringVar(&flagAppendTarget)
+	cmdAppend.Flag("vault", "Binary vault file to append, as written by create --binary").
+		StringVar(&flagAppendVault)
+	addBuilderFlags(cmdAppend)
+
+	cmdBuild := appGoblin.Command("build", "Build all of the vaults described in a manifest")
+	cmdBuild.Flag("manifest", "Manifest file describing the vaults to build").Short('m').
+		Default("goblin.yaml").StringVar(&flagManifest)
+	addReportFlags(cmdBuild)
+	cmdBuild.Flag("check", "Check the output files are up to date instead of writing them").
+		BoolVar(&flagCheck)
+
+	cmd, err := appGoblin.Parse(os.Args[1:])
+	if err != nil {
+		fatal("Could not parse command line arguments", "error", err)
+	}
+	configureLogger()
+
+	switch cmd {
+	case cmdCreate.FullCommand():
+		runCreate()
+	case cmdAppend.FullCommand():
+		runAppend()
+	case cmdBuild.FullCommand():
+		runBuild()
+	}
+}
+
+// addBuilderFlags adds the flags for including files in a vault and the options it's
+// built with, which are shared by the commands that build a vault from the command
+// line.
+func addBuilderFlags(cmd *kingpin.CmdClause) {
+	cmd.Flag("include-root", "Root path to use when including files in the vault").Short('r').
+		StringVar(&flagIncludeRoot)
+	cmd.Flag("include", "Files to include in the vault").Short('i').
+		StringsVar(&flagIncludes)
+	cmd.Flag("include-dir", "Directory to include recursively in the vault").Short('d').
+		StringsVar(&flagIncludeDirs)
+	cmd.Flag("map", "Include a directory, relative to the include root, under a vault prefix (dir=prefix[;strip=N][;rename=regex=replacement])").
+		StringsVar(&flagMaps)
+	cmd.Flag("strip-segments", "Number of leading directories to remove from all included paths").
+		IntVar(&flagStripSegments)
+	cmd.Flag("rename", "Rename all included paths matching a regex (regex=replacement)").
+		StringsVar(&flagRenames)
+	cmd.Flag("max-depth", "Maximum depth to include directories recursively, 0 for no limit").
+		IntVar(&flagMaxDepth)
+	cmd.Flag("exclude", "Exclude files matching a .gitignore style pattern").Short('x').
+		StringsVar(&flagExcludes)
+	cmd.Flag("ignore-file", "Read exclude patterns from a file in the include root, such as .gitignore").
+		StringsVar(&flagIgnoreFiles)
+	cmd.Flag("compression", "Compression level for the vault (none, fastest, default, best or 0-9)").
+		StringVar(&flagCompression)
+	cmd.Flag("mtime", "Clamp file modified times to a Unix timestamp or RFC 3339 time, defaults to $SOURCE_DATE_EPOCH").
+		StringVar(&flagModTime)
+	cmd.Flag("precompress", "Also store compressed variants of compressible files").
+		EnumsVar(&flagPrecompress, goblin.EncodingGzip, goblin.EncodingDeflate)
+	cmd.Flag("fingerprint", "Add content-hashed aliases for files matching a glob").
+		StringsVar(&flagFingerprints)
+	cmd.Flag("transform", "Transform files matching a glob before including them (glob=compact-json, glob=lf, glob=trim-trailing-space or glob=exec:command args)").
+		StringsVar(&flagTransforms)
+	cmd.Flag("max-size", "Fail if the files in the vault are larger than a size, such as 50MB").
+		StringVar(&flagMaxSize)
+	cmd.Flag("max-file-size", "Fail if a file is larger than a size, such as 1MiB").
+		StringVar(&flagMaxFileSize)
+	cmd.Flag("budget", "Fail if the files matching a glob are larger than a size (glob=size)").
+		StringsVar(&flagBudgets)
+}
+
+// addReportFlags adds the flags for printing a size report after building vaults.
+func addReportFlags(cmd *kingpin.CmdClause) {
+	cmd.Flag("report", "Print a report of the vault's size (text or json)").
+		EnumVar(&flagReport, reportFormatText, reportFormatJSON)
+	cmd.Flag("report-file", "Write the size report to a file instead of stdout").
+		StringVar(&flagReportFile)
+	cmd.Flag("report-top", "Number of the largest files to include in the size report").
+		Default("10").IntVar(&flagReportTop)
+}
+
+// configureLogger sets up the logger from the logging flags.
+func configureLogger() {
+	if flagQuiet && flagVerbose {
+		fatal("--quiet and --verbose cannot be used together")
+	}
+
+	level := logging.LevelInfo
+	if flagQuiet {
+		level = logging.LevelWarn
+	} else if flagVerbose {
+		level = logging.LevelDebug
+	}
+
+	format := logging.FormatText
+	if flagLogFormat == logFormatJSON {
+		format = logging.FormatJSON
+	}
+
+	logger = logging.New(os.Stderr, level, format)
+}
+
+// fatal logs an error message and exits.
+func fatal(msg string, args ...interface{}) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
+
+func runAppend() {
+	if flagAppendVault != "" && (len(flagIncludes) > 0 || len(flagIncludeDirs) > 0 || len(flagMaps) > 0) {
+		fatal("--vault cannot be used with --include, --include-dir or --map")
+	}
+
+	if flagAppendVault != "" {
+		vaultData, err := ioutil.ReadFile(flagAppendVault)
+		if err != nil {
+			fatal("Could not read vault file", "path", flagAppendVault, "error", err)
+		}
+
+		err = goblin.AppendVaultData(flagAppendTarget, vaultData)
+		if err != nil {
+			fatal("Error appending vault", "error", err)
+		}
+		return
+	}
+
+	opts, err := builderOptions(clampModTime(flagModTime))
+	if err != nil {
+		fatal("Invalid option", "error", err)
+	}
+
+	b := goblin.NewMemoryBuilder(opts...)
+	err = includeFiles(b)
+	if err != nil {
+		fatal("Error building vault", "error", err)
+	}
+
+	err = b.AppendToFile(flagAppendTarget)
+	if err != nil {
+		fatal("Error appending vault", "error", err)
+	}
+}
+
+// builderOptions returns the builder options from the flags added by addBuilderFlags.
+func builderOptions(modTime time.Time) ([]goblin.MemoryBuilderOption, error) {
+	compressionLevel, err := manifest.ParseCompression(flagCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []goblin.MemoryBuilderOption{
+		goblin.MemoryBuilderLogger(logger),
+		goblin.MemoryBuilderCompressionLevel(compressionLevel),
+		goblin.MemoryBuilderClampModTime(modTime),
+		goblin.MemoryBuilderPrecompress(flagPrecompress...),
+		goblin.MemoryBuilderFingerprint(flagFingerprints...),
+		goblin.MemoryBuilderExclude(flagExcludes...),
+		goblin.MemoryBuilderIgnoreFiles(flagIgnoreFiles...),
+		goblin.MemoryBuilderMaxDepth(flagMaxDepth),
+	}
+
+	transformOpts, err := parseTransforms(flagTransforms)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, transformOpts...)
+
+	sizeOpts, err := sizeOptions(flagMaxSize, flagMaxFileSize, flagBudgets)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(opts, sizeOpts...), nil
+}
+
+// includeFiles includes the files and directories from the command line flags in
+// the builder.
+func includeFiles(b *goblin.MemoryBuilder) error {
+	var incOpts []goblin.IncludeOption
+	if flagStripSegments > 0 {
+		incOpts = append(incOpts, goblin.IncludeStripSegments(flagStripSegments))
+	}
+	for _, rename := range flagRenames {
+		pattern, replacement, err := parseRename(rename)
+		if err != nil {
+			return fmt.Errorf("invalid rename %s: %w", rename, err)
+		}
+		incOpts = append(incOpts, goblin.IncludeRename(pattern, replacement))
+	}
+
+	err := b.Include(flagIncludeRoot, flagIncludes, incOpts...)
+	if err != nil {
+		return fmt.Errorf("error including files: %w", err)
+	}
+	err = b.IncludeDirs(flagIncludeRoot, flagIncludeDirs, incOpts...)
+	if err != nil {
+		return fmt.Errorf("error including directories: %w", err)
+	}
+
+	for _, mapping := range flagMaps {
+		root, err := parseMapRoot(mapping)
+		if err != nil {
+			return fmt.Errorf("invalid map %s: %w", mapping, err)
+		}
+		mapOpts := append([]goblin.IncludeOption{goblin.IncludePrefix(root.prefix)}, incOpts...)
+		mapOpts = append(mapOpts, root.opts...)
+
+		err = b.IncludeDirs(filepath.Join(flagIncludeRoot, root.dir), []string{"."}, mapOpts...)
+		if err != nil {
+			return fmt.Errorf("error including mapped directory %s: %w", root.dir, err)
+		}
+	}
+
+	return nil
+}
+
+// mapRoot is a directory included under a prefix in the vault with its own rules for
+// changing the paths of its files.
+type mapRoot struct {
+	dir    string
+	prefix string
+	opts   []goblin.IncludeOption
+}
+
+// parseMapRoot parses a mapping in the form "dir=prefix", optionally followed by
+// rules for the directory separated by semicolons: "strip=N" to remove leading
+// directories and "rename=regex=replacement" to rename paths. A strip rule replaces
+// --strip-segments for the directory and renames are applied after any from --rename.
+func parseMapRoot(mapping string) (mapRoot, error) {
+	parts := strings.Split(mapping, ";")
+
+	var root mapRoot
+	root.dir, root.prefix = parseMapping(parts[0])
+	if root.dir == "" {
+		return mapRoot{}, fmt.Errorf("expected dir=prefix")
+	}
+
+	for _, part := range parts[1:] {
+		key, value := parseMapping(part)
+		switch key {
+		case "strip":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 0 {
+				return mapRoot{}, fmt.Errorf("invalid strip %s: expected a number of segments", value)
+			}
+			root.opts = append(root.opts, goblin.IncludeStripSegments(count))
+		case "rename":
+			pattern, replacement, err := parseRename(value)
+			if err != nil {
+				return mapRoot{}, fmt.Errorf("invalid rename %s: %w", value, err)
+			}
+			root.opts = append(root.opts, goblin.IncludeRename(pattern, replacement))
+		default:
+			return mapRoot{}, fmt.Errorf("unknown rule %s, expected strip=N or rename=regex=replacement", part)
+		}
+	}
+
+	return root, nil
+}
+
+// parseMapping parses a mapping in the form "dir=prefix". If there's no prefix, the
+// files are included at the root of the vault.
+func parseMapping(mapping string) (string, string) {
+	idx := strings.Index(mapping, "=")
+	if idx < 0 {
+		return mapping, ""
+	}
+
+	return mapping[:idx], mapping[idx+1:]
+}
+
+// parseTransforms parses transforms in the form "glob=transform", where the transform
+// is the name of a built-in transform or "exec:" followed by a command and its
+// arguments separated by spaces.
+func parseTransforms(transforms []string) ([]goblin.MemoryBuilderOption, error) {
+	var opts []goblin.MemoryBuilderOption
+	for _, transform := range transforms {
+		idx := strings.Index(transform, "=")
+		if idx < 0 {
+			return nil, fmt.Errorf("invalid transform %s: expected glob=transform", transform)
+		}
+		glob, name := transform[:idx], transform[idx+1:]
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid transform %s: %w", transform, err)
+		}
+
+		var transformer goblin.Transformer
+		if strings.HasPrefix(name, execTransformPrefix) {
+			command := strings.Fields(strings.TrimPrefix(name, execTransformPrefix))
+			if len(command) == 0 {
+				return nil, fmt.Errorf("invalid transform %s: exec requires a command", transform)
+			}
+			transformer = goblin.ExecTransformer(command[0], command[1:]...)
+		} else {
+			var err error
+			transformer, err = goblin.BuiltinTransformer(name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid transform %s: %w", transform, err)
+			}
+		}
+
+		opts = append(opts, goblin.MemoryBuilderTransform(glob, transformer))
+	}
 
-	_, err := appGoblin.Parse(os.Args[1:])
+	return opts, nil
+}
+
+// parseRename parses a rename in the form "regex=replacement". The last "=" separates
+// the pattern from the replacement, which may be empty.
+func parseRename(rename string) (*regexp.Regexp, string, error) {
+	idx := strings.LastIndex(rename, "=")
+	if idx < 0 {
+		return nil, "", fmt.Errorf("expected regex=replacement")
+	}
+
+	pattern, err := regexp.Compile(rename[:idx])
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Could not parse command line arguments: %s\n", err)
-		os.Exit(1)
+		return nil, "", err
+	}
+
+	return pattern, rename[idx+1:], nil
+}
+
+func runCreate() {
+	if flagBinary && flagEmbed {
+		fatal("--binary and --embed cannot be used together")
+	}
+	if flagCheck && flagWatch {
+		fatal("--check and --watch cannot be used together")
+	}
+	if flagWatch && flagWatchInterval <= 0 {
+		fatal("--watch-interval must be greater than zero", "interval", flagWatchInterval)
+	}
+	if flagWatch && flagDebounce < 0 {
+		fatal("--debounce cannot be negative", "debounce", flagDebounce)
+	}
+	if flagOut == stdoutPath && (flagEmbed || flagWatch || flagCheck) {
+		fatal("--out - cannot be used with --embed, --watch or --check")
+	}
+	if flagOut == stdoutPath && flagReport != "" && flagReportFile == "" {
+		fatal("--out - requires --report-file when using --report")
 	}
 	if flagPackage == "" {
 		flagPackage = flagName
@@ -50,37 +459,106 @@ func main() {
 		flagOut = fmt.Sprintf("goblin_%s.go", flagName)
 	}
 
-	logger := logging.NewPrintfLogger()
-
-	b := goblin.NewMemoryBuilder(
-		goblin.MemoryBuilderLogger(logger),
+	modTime := clampModTime(flagModTime)
+	if flagCheck && modTime.IsZero() {
+		// Modified times are stored in the vault, so without clamping them a fresh
+		// checkout of unchanged files would never match the existing output.
+		fatal("--check requires --mtime or SOURCE_DATE_EPOCH so modified times are reproducible")
+	}
+	opts, err := builderOptions(modTime)
+	if err != nil {
+		fatal("Invalid option", "error", err)
+	}
+	opts = append(opts,
 		goblin.MemoryBuilderExportLoader(flagExportLoader),
+		goblin.MemoryBuilderAccessors(flagAccessors),
+		goblin.MemoryBuilderLoaderFormat(parseLoaderFormat(flagLoaderFormat)),
 	)
-	err = b.Include(flagIncludeRoot, flagIncludes)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error including files: %s\n", err)
-		os.Exit(1)
+
+	build := func() (*goblin.MemoryBuilder, error) {
+		b := goblin.NewMemoryBuilder(opts...)
+		return b, includeFiles(b)
 	}
 
-	f, err := os.OpenFile(flagOut, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if flagWatch {
+		w := &vaultWatcher{
+			name:   flagName,
+			logger: logger,
+			build:  build,
+			write: func(b *goblin.MemoryBuilder) error {
+				return writeOutput(b, flagPackage, flagName, flagOut, flagBinary, flagEmbed)
+			},
+			outputs:   outputPaths(flagOut, flagEmbed),
+			cachePath: flagCacheFile,
+			key:       watchCacheKey(os.Args[1:], modTime),
+			interval:  flagWatchInterval,
+			debounce:  flagDebounce,
+		}
+		if w.cachePath == "" {
+			w.cachePath = filepath.Join(filepath.Dir(flagOut), "."+filepath.Base(flagOut)+".cache")
+		}
+
+		err = w.run()
+		if err != nil {
+			fatal("Error watching vault", "error", err)
+		}
+		return
+	}
+
+	b, err := build()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Could not open output file %s: %s\n", flagOut, err)
-		os.Exit(1)
+		fatal("Error building vault", "error", err)
 	}
-	defer f.Close()
 
-	if flagBinary {
-		err = b.WriteBinary(f)
+	if flagCheck {
+		upToDate, err := checkOutput(b, flagPackage, flagName, flagOut, flagBinary, flagEmbed)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing binary file: %s\n", err)
+			fatal("Error checking vault", "error", err)
+		}
+		if !upToDate {
 			os.Exit(1)
 		}
+		return
+	}
 
-	} else {
-		err = b.WriteLoader(flagPackage, flagName, f)
+	err = writeOutput(b, flagPackage, flagName, flagOut, flagBinary, flagEmbed)
+	if err != nil {
+		fatal("Error writing vault", "error", err)
+	}
+
+	if flagReport != "" {
+		report, err := b.Report(flagReportTop)
+		if err == nil {
+			err = writeReports([]namedReport{{name: flagName, report: report}}, flagReport, flagReportFile)
+		}
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing code file: %s\n", err)
-			os.Exit(1)
+			fatal("Error writing size report", "error", err)
 		}
 	}
 }
+
+// clampModTime returns the time to clamp modified times to from the provided value or,
+// if it's empty, the SOURCE_DATE_EPOCH environment variable. It exits if the time is
+// invalid.
+func clampModTime(modTime string) time.Time {
+	var t time.Time
+	var err error
+	if modTime != "" {
+		t, err = manifest.ParseModTime(modTime)
+	} else {
+		t, err = goblin.SourceDateEpoch()
+	}
+	if err != nil {
+		fatal("Invalid option", "error", err)
+	}
+
+	return t
+}
+
+func parseLoaderFormat(format string) goblin.LoaderFormat {
+	if format == goblin.LoaderFormatString.String() {
+		return goblin.LoaderFormatString
+	}
+
+	return goblin.LoaderFormatBytes
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aphistic/goblin"
+)
+
+func TestParseMapping(t *testing.T) {
+	tests := []struct {
+		mapping string
+		dir     string
+		prefix  string
+	}{
+		{mapping: "dist=static", dir: "dist", prefix: "static"},
+		{mapping: "dist", dir: "dist", prefix: ""},
+		{mapping: "dist=", dir: "dist", prefix: ""},
+		{mapping: "dist=a=b", dir: "dist", prefix: "a=b"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.mapping, func(t *testing.T) {
+			dir, prefix := parseMapping(test.mapping)
+			assert.Equal(t, test.dir, dir)
+			assert.Equal(t, test.prefix, prefix)
+		})
+	}
+}
+
+func TestParseRename(t *testing.T) {
+	t.Run("rename", func(t *testing.T) {
+		pattern, replacement, err := parseRename(`^(\w+)=v1/$1`)
+		require.NoError(t, err)
+		assert.Equal(t, `^(\w+)`, pattern.String())
+		assert.Equal(t, "v1/$1", replacement)
+	})
+
+	t.Run("empty replacement", func(t *testing.T) {
+		pattern, replacement, err := parseRename(`\.min=`)
+		require.NoError(t, err)
+		assert.Equal(t, `\.min`, pattern.String())
+		assert.Equal(t, "", replacement)
+	})
+
+	t.Run("missing replacement", func(t *testing.T) {
+		_, _, err := parseRename(`\.min`)
+		assert.EqualError(t, err, "expected regex=replacement")
+	})
+
+	t.Run("invalid regex", func(t *testing.T) {
+		_, _, err := parseRename(`(=x`)
+		assert.EqualError(t, err, "error parsing regexp: missing closing ): `(`")
+	})
+}
+
+func TestParseTransforms(t *testing.T) {
+	t.Run("transforms are applied", func(t *testing.T) {
+		td, err := ioutil.TempDir("", testTempPattern)
+		require.NoError(t, err)
+		defer os.RemoveAll(td)
+
+		writeTestFiles(t, td, map[string]string{
+			"data.json": "{ \"a\": 1 }\r\n",
+		})
+
+		opts, err := parseTransforms([]string{"*.json=lf", "*.json=compact-json", "*.txt=exec:tr a b"})
+		require.NoError(t, err)
+		assert.Len(t, opts, 3)
+
+		b := goblin.NewMemoryBuilder(opts...)
+		require.NoError(t, b.Include(td, []string{"*"}))
+
+		existing := goblin.NewMemoryVault()
+		require.NoError(t, existing.WriteFile("data.json", bytes.NewBufferString(`{"a":1}`)))
+		changes, err := b.Diff(existing)
+		require.NoError(t, err)
+		assert.Empty(t, changes)
+	})
+
+	tests := []struct {
+		name      string
+		transform string
+		expected  string
+	}{
+		{
+			name:      "missing transform",
+			transform: "*.json",
+			expected:  "invalid transform *.json: expected glob=transform",
+		},
+		{
+			name:      "invalid glob",
+			transform: "[=lf",
+			expected:  "invalid transform [=lf: syntax error in pattern",
+		},
+		{
+			name:      "missing command",
+			transform: "*.js=exec: ",
+			expected:  "invalid transform *.js=exec: : exec requires a command",
+		},
+		{
+			name:      "unknown transform",
+			transform: "*.js=minify",
+			expected:  "invalid transform *.js=minify: unknown transform minify",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseTransforms([]string{test.transform})
+			assert.EqualError(t, err, test.expected)
+		})
+	}
+}
+
+func TestParseMapRoot(t *testing.T) {
+	t.Run("rules", func(t *testing.T) {
+		root, err := parseMapRoot(`frontend/dist=static;strip=1;rename=\.min\.js$=.js`)
+		require.NoError(t, err)
+		assert.Equal(t, "frontend/dist", root.dir)
+		assert.Equal(t, "static", root.prefix)
+		assert.Len(t, root.opts, 2)
+	})
+
+	t.Run("rules are applied to the directory", func(t *testing.T) {
+		td, err := ioutil.TempDir("", testTempPattern)
+		require.NoError(t, err)
+		defer os.RemoveAll(td)
+
+		writeTestFiles(t, td, map[string]string{
+			"dist/js/app.min.js": "app",
+		})
+
+		root, err := parseMapRoot(`dist=static;strip=1;rename=\.min\.js$=.js`)
+		require.NoError(t, err)
+
+		b := goblin.NewMemoryBuilder()
+		opts := append([]goblin.IncludeOption{goblin.IncludePrefix(root.prefix)}, root.opts...)
+		require.NoError(t, b.IncludeDirs(filepath.Join(td, root.dir), []string{"."}, opts...))
+
+		report, err := b.Report(10)
+		require.NoError(t, err)
+		require.Len(t, report.Largest, 1)
+		assert.Equal(t, "static/app.js", report.Largest[0].Path)
+	})
+
+	tests := []struct {
+		name     string
+		mapping  string
+		expected string
+	}{
+		{name: "missing dir", mapping: "=static", expected: "expected dir=prefix"},
+		{
+			name:     "invalid strip",
+			mapping:  "dist=static;strip=a",
+			expected: "invalid strip a: expected a number of segments",
+		},
+		{
+			name:     "negative strip",
+			mapping:  "dist=static;strip=-1",
+			expected: "invalid strip -1: expected a number of segments",
+		},
+		{
+			name:     "invalid rename",
+			mapping:  "dist=static;rename=[=x",
+			expected: "invalid rename [=x: error parsing regexp: missing closing ]: `[`",
+		},
+		{
+			name:     "unknown rule",
+			mapping:  "dist=static;depth=1",
+			expected: "unknown rule depth=1, expected strip=N or rename=regex=replacement",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseMapRoot(test.mapping)
+			assert.EqualError(t, err, test.expected)
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aphistic/goblin"
+	"github.com/aphistic/goblin/internal/atomicfile"
+)
+
+// outputFile is a file written for a vault's output.
+type outputFile struct {
+	path string
+	data []byte
+}
+
+// outputPaths returns the paths of the files written for a vault's output file.
+func outputPaths(out string, embed bool) []string {
+	if embed {
+		return []string{embedBinaryPath(out), out}
+	}
+
+	return []string{out}
+}
+
+// embedBinaryPath returns the path of the binary file written next to a go:embed
+// loader.
+func embedBinaryPath(out string) string {
+	return strings.TrimSuffix(out, filepath.Ext(out)) + ".bin"
+}
+
+// renderOutput renders the files written for the vault being built: a binary vault, a
+// go:embed loader with a binary file next to it or a generated loader. Files are in
+// the order they should be written, so a loader is never written before its data.
+func renderOutput(
+	b *goblin.MemoryBuilder, packageName string, name string, out string, binary bool, embed bool,
+) ([]outputFile, error) {
+	if binary {
+		buf := bytes.NewBuffer(nil)
+		err := b.WriteBinary(buf)
+		if err != nil {
+			return nil, fmt.Errorf("error writing binary file %s: %w", out, err)
+		}
+
+		return []outputFile{{path: out, data: buf.Bytes()}}, nil
+	}
+
+	if embed {
+		binPath := embedBinaryPath(out)
+		codeBuf := bytes.NewBuffer(nil)
+		binBuf := bytes.NewBuffer(nil)
+		err := b.WriteEmbedLoader(packageName, name, filepath.Base(binPath), codeBuf, binBuf)
+		if err != nil {
+			return nil, fmt.Errorf("error writing embed files %s and %s: %w", out, binPath, err)
+		}
+
+		return []outputFile{
+			{path: binPath, data: binBuf.Bytes()},
+			{path: out, data: codeBuf.Bytes()},
+		}, nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err := b.WriteLoader(packageName, name, buf)
+	if err != nil {
+		return nil, fmt.Errorf("error writing code file %s: %w", out, err)
+	}
+
+	return []outputFile{{path: out, data: buf.Bytes()}}, nil
+}
+
+// writeOutput writes the vault being built to the output file as a binary vault, a
+// go:embed loader with a binary file next to it or a generated loader. Files are
+// replaced atomically, so a build running at the same time never reads a partially
+// written file. An output of "-" writes a binary vault or generated loader to stdout.
+func writeOutput(
+	b *goblin.MemoryBuilder, packageName string, name string, out string, binary bool, embed bool,
+) error {
+	if out == stdoutPath && embed {
+		return fmt.Errorf("go:embed loaders cannot be written to stdout")
+	}
+
+	files, err := renderOutput(b, packageName, name, out, binary, embed)
+	if err != nil {
+		return err
+	}
+
+	if out == stdoutPath {
+		_, err = os.Stdout.Write(files[0].data)
+		return err
+	}
+
+	for _, f := range files {
+		err = atomicfile.WriteFile(f.path, 0644, func(w io.Writer) error {
+			_, err := w.Write(f.data)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("error writing %s: %w", f.path, err)
+		}
+	}
+
+	return nil
+}
+
+// checkOutput renders the vault being built the same way writeOutput does and
+// compares it to the existing output files without writing anything. Since builds are
+// reproducible, any difference means the output is out of date, including changes to
+// options that only affect the generated code. If they differ, the differences are
+// logged and false is returned.
+func checkOutput(
+	b *goblin.MemoryBuilder, packageName string, name string, out string, binary bool, embed bool,
+) (bool, error) {
+	files, err := renderOutput(b, packageName, name, out, binary, embed)
+	if err != nil {
+		return false, err
+	}
+
+	upToDate := true
+	for _, f := range files {
+		existing, err := ioutil.ReadFile(f.path)
+		if os.IsNotExist(err) {
+			logger.Warn("Vault output is missing", "vault", name, "path", f.path)
+			upToDate = false
+			continue
+		} else if err != nil {
+			return false, fmt.Errorf("could not read output file %s: %w", f.path, err)
+		}
+
+		if !bytes.Equal(existing, f.data) {
+			logger.Warn("Vault output is out of date", "vault", name, "path", f.path)
+			upToDate = false
+		}
+	}
+	if upToDate {
+		return true, nil
+	}
+
+	changes, err := diffOutput(b, name, out, binary)
+	if err != nil {
+		// The existing output may be missing or from an older version, so the
+		// differences just can't be explained.
+		logger.Debug("Could not compare vault files", "vault", name, "error", err)
+		return false, nil
+	}
+	for _, change := range changes {
+		logger.Warn("Vault file differs", "vault", name, "change", change.Type, "path", change.Path)
+	}
+	if len(changes) == 0 {
+		logger.Warn("Vault files are unchanged, but the generated output or modified times differ",
+			"vault", name)
+	}
+
+	return false, nil
+}
+
+// diffOutput returns the differences between the files in the vault being built and
+// the vault in the existing output file.
+func diffOutput(b *goblin.MemoryBuilder, name string, out string, binary bool) ([]goblin.VaultChange, error) {
+	var vaultData []byte
+	var err error
+	if binary {
+		vaultData, err = ioutil.ReadFile(out)
+	} else {
+		vaultData, err = goblin.ReadLoaderData(out, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := goblin.LoadMemoryVault(vaultData)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Diff(existing)
+}
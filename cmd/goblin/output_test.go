@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aphistic/goblin"
+	"github.com/aphistic/goblin/internal/logging"
+)
+
+const testTempPattern = "goblin-cmd-test-*"
+
+// useTestLogger replaces the logger with one writing to the returned buffer until the
+// test finishes.
+func useTestLogger(t *testing.T) *bytes.Buffer {
+	buf := bytes.NewBuffer(nil)
+	prevLogger := logger
+	logger = logging.New(buf, logging.LevelDebug, logging.FormatText)
+	t.Cleanup(func() {
+		logger = prevLogger
+	})
+
+	return buf
+}
+
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	for name, data := range files {
+		filePath := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+		require.NoError(t, ioutil.WriteFile(filePath, []byte(data), 0644))
+	}
+}
+
+func TestCheckOutput(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	srcDir := filepath.Join(td, "src")
+	writeTestFiles(t, srcDir, map[string]string{
+		"index.html": "index",
+		"app.js":     "app",
+	})
+
+	modTime := time.Date(2020, 4, 8, 0, 0, 0, 0, time.UTC)
+	newBuilder := func(opts ...goblin.MemoryBuilderOption) *goblin.MemoryBuilder {
+		b := goblin.NewMemoryBuilder(append([]goblin.MemoryBuilderOption{
+			goblin.MemoryBuilderClampModTime(modTime),
+		}, opts...)...)
+		require.NoError(t, b.Include(srcDir, []string{"*"}))
+		return b
+	}
+
+	tests := []struct {
+		name   string
+		binary bool
+		embed  bool
+	}{
+		{name: "code"},
+		{name: "binary", binary: true},
+		{name: "embed", embed: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name+" up to date", func(t *testing.T) {
+			useTestLogger(t)
+			out := filepath.Join(td, test.name+".go")
+			require.NoError(t, writeOutput(newBuilder(), "assets", "assets", out, test.binary, test.embed))
+
+			upToDate, err := checkOutput(newBuilder(), "assets", "assets", out, test.binary, test.embed)
+			require.NoError(t, err)
+			assert.True(t, upToDate)
+		})
+	}
+
+	t.Run("changed options", func(t *testing.T) {
+		logs := useTestLogger(t)
+		out := filepath.Join(td, "options.go")
+		require.NoError(t, writeOutput(newBuilder(), "assets", "assets", out, false, false))
+
+		upToDate, err := checkOutput(
+			newBuilder(goblin.MemoryBuilderExportLoader(true)), "assets", "assets", out, false, false,
+		)
+		require.NoError(t, err)
+		assert.False(t, upToDate)
+		assert.Contains(t, logs.String(), "WARN: Vault output is out of date vault=assets path="+out)
+		assert.Contains(t, logs.String(), "Vault files are unchanged")
+	})
+
+	t.Run("changed package", func(t *testing.T) {
+		useTestLogger(t)
+		out := filepath.Join(td, "package.go")
+		require.NoError(t, writeOutput(newBuilder(), "assets", "assets", out, false, false))
+
+		upToDate, err := checkOutput(newBuilder(), "web", "assets", out, false, false)
+		require.NoError(t, err)
+		assert.False(t, upToDate)
+	})
+
+	t.Run("changed mod time", func(t *testing.T) {
+		useTestLogger(t)
+		out := filepath.Join(td, "mtime.go")
+		require.NoError(t, writeOutput(newBuilder(), "assets", "assets", out, false, false))
+
+		b := newBuilder(goblin.MemoryBuilderClampModTime(modTime.Add(-time.Hour)))
+		upToDate, err := checkOutput(b, "assets", "assets", out, false, false)
+		require.NoError(t, err)
+		assert.False(t, upToDate)
+	})
+
+	t.Run("changed files", func(t *testing.T) {
+		logs := useTestLogger(t)
+		out := filepath.Join(td, "files.go")
+		require.NoError(t, writeOutput(newBuilder(), "assets", "assets", out, false, false))
+
+		b := newBuilder()
+		require.NoError(t, b.IncludeDirs(td, []string{"src"}))
+
+		upToDate, err := checkOutput(b, "assets", "assets", out, false, false)
+		require.NoError(t, err)
+		assert.False(t, upToDate)
+		assert.Contains(t, logs.String(), "WARN: Vault file differs vault=assets change=added path=src/app.js")
+	})
+
+	t.Run("missing embed binary", func(t *testing.T) {
+		logs := useTestLogger(t)
+		out := filepath.Join(td, "missing.go")
+		require.NoError(t, writeOutput(newBuilder(), "assets", "assets", out, false, true))
+		require.NoError(t, os.Remove(embedBinaryPath(out)))
+
+		upToDate, err := checkOutput(newBuilder(), "assets", "assets", out, false, true)
+		require.NoError(t, err)
+		assert.False(t, upToDate)
+		assert.Contains(t, logs.String(), "WARN: Vault output is missing vault=assets path="+embedBinaryPath(out))
+	})
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aphistic/goblin"
+	"github.com/aphistic/goblin/internal/manifest"
+)
+
+const (
+	reportFormatText = "text"
+	reportFormatJSON = "json"
+)
+
+// sizeOptions returns the builder options for a maximum vault size, a maximum file
+// size and budgets in the form "glob=size". Empty sizes have no limit.
+func sizeOptions(maxSize string, maxFileSize string, budgets []string) ([]goblin.MemoryBuilderOption, error) {
+	var opts []goblin.MemoryBuilderOption
+
+	if maxSize != "" {
+		size, err := manifest.ParseSize(maxSize)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, goblin.MemoryBuilderMaxSize(size))
+	}
+	if maxFileSize != "" {
+		size, err := manifest.ParseSize(maxFileSize)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, goblin.MemoryBuilderMaxFileSize(size))
+	}
+
+	for _, budget := range budgets {
+		glob, sizeStr := parseMapping(budget)
+		if sizeStr == "" {
+			return nil, fmt.Errorf("invalid budget %s: expected glob=size", budget)
+		}
+		size, err := manifest.ParseSize(sizeStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid budget %s: %w", budget, err)
+		}
+		opts = append(opts, goblin.MemoryBuilderSizeBudget(glob, size))
+	}
+
+	return opts, nil
+}
+
+// namedReport is the size report for a vault.
+type namedReport struct {
+	name   string
+	report *goblin.SizeReport
+}
+
+// writeReports writes the size reports to the report file, or stdout if there isn't
+// one. A single report is written on its own, while multiple reports are written as
+// a JSON object keyed by vault name or under a heading for each vault.
+func writeReports(reports []namedReport, format string, reportFile string) error {
+	var w io.Writer = os.Stdout
+	if reportFile != "" {
+		f, err := os.Create(reportFile)
+		if err != nil {
+			return fmt.Errorf("could not create report file %s: %w", reportFile, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if format == reportFormatJSON {
+		var v interface{}
+		if len(reports) == 1 {
+			v = reports[0].report
+		} else {
+			byName := map[string]*goblin.SizeReport{}
+			for _, r := range reports {
+				byName[r.name] = r.report
+			}
+			v = byName
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	for idx, r := range reports {
+		if idx > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "Vault %s:\n", r.name)
+
+		err := r.report.WriteText(w)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aphistic/goblin"
+)
+
+func TestSizeOptions(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	writeTestFiles(t, td, map[string]string{
+		"app.js":    "0123456789",
+		"logo.png":  "01234567890123456789",
+		"other.png": "01234567890123456789",
+	})
+
+	t.Run("no limits", func(t *testing.T) {
+		opts, err := sizeOptions("", "", nil)
+		require.NoError(t, err)
+		assert.Empty(t, opts)
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			maxSize     string
+			maxFileSize string
+			budgets     []string
+			include     []string
+			expected    string
+		}{
+			{
+				name:    "max size",
+				maxSize: "25B",
+				include: []string{"app.js", "logo.png"},
+				expected: "including logo.png makes the vault 30 B, " +
+					"larger than the maximum size of 25 B",
+			},
+			{
+				name:        "max file size",
+				maxFileSize: "15B",
+				include:     []string{"app.js", "logo.png"},
+				expected:    "logo.png is 20 B, larger than the maximum file size of 15 B",
+			},
+			{
+				name:    "budget",
+				budgets: []string{"*.png=30B"},
+				include: []string{"app.js", "logo.png", "other.png"},
+				expected: "including other.png makes files matching *.png 40 B, " +
+					"larger than their budget of 30 B",
+			},
+			{
+				name:    "within limits",
+				maxSize: "1KB",
+				budgets: []string{"*.png=1KB"},
+				include: []string{"app.js", "logo.png", "other.png"},
+			},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				opts, err := sizeOptions(test.maxSize, test.maxFileSize, test.budgets)
+				require.NoError(t, err)
+
+				b := goblin.NewMemoryBuilder(opts...)
+				err = b.Include(td, test.include)
+				if test.expected == "" {
+					assert.NoError(t, err)
+				} else {
+					assert.EqualError(t, err, test.expected)
+				}
+			})
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			maxSize     string
+			maxFileSize string
+			budgets     []string
+			expected    string
+		}{
+			{
+				name:     "invalid max size",
+				maxSize:  "big",
+				expected: "invalid size big, expected a number of bytes such as 500KB or 10MiB",
+			},
+			{
+				name:        "invalid max file size",
+				maxFileSize: "-1",
+				expected:    "invalid size -1, expected a number of bytes such as 500KB or 10MiB",
+			},
+			{
+				name:     "missing budget size",
+				budgets:  []string{"*.png"},
+				expected: "invalid budget *.png: expected glob=size",
+			},
+			{
+				name:    "invalid budget size",
+				budgets: []string{"*.png=lots"},
+				expected: "invalid budget *.png=lots: " +
+					"invalid size lots, expected a number of bytes such as 500KB or 10MiB",
+			},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				_, err := sizeOptions(test.maxSize, test.maxFileSize, test.budgets)
+				assert.EqualError(t, err, test.expected)
+			})
+		}
+	})
+}
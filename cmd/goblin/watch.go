@@ -0,0 +1,190 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aphistic/goblin"
+	"github.com/aphistic/goblin/internal/atomicfile"
+	"github.com/aphistic/goblin/internal/inputcache"
+	"github.com/aphistic/goblin/internal/logging"
+)
+
+// vaultWatcher rebuilds a vault whenever the files it's built from change.
+type vaultWatcher struct {
+	name   string
+	logger *logging.Logger
+	// build creates a builder and includes the vault's files. The builder is
+	// returned even if including fails so the files read so far can be watched.
+	build func() (*goblin.MemoryBuilder, error)
+	write func(*goblin.MemoryBuilder) error
+
+	outputs   []string
+	cachePath string
+	key       string
+	interval  time.Duration
+	debounce  time.Duration
+
+	// ticks triggers each check for changes and now returns the current time for
+	// debouncing. They default to a ticker with the watch interval and time.Now, and
+	// are replaced in tests. Watching stops if ticks is closed.
+	ticks <-chan time.Time
+	now   func() time.Time
+
+	inputs inputcache.State
+}
+
+// watchCacheKey returns a key identifying the options a vault is built with, so a
+// cache written with different options isn't used.
+func watchCacheKey(args []string, modTime time.Time) string {
+	keyParts := append(append([]string(nil), args...), modTime.String())
+	h := sha256.Sum256([]byte(strings.Join(keyParts, "\x00")))
+	return hex.EncodeToString(h[:])
+}
+
+// run builds the vault, unless the cache shows it's up to date, then polls its inputs
+// and rebuilds it after they change until the process is stopped.
+func (w *vaultWatcher) run() error {
+	upToDate, err := w.loadCache()
+	if err != nil {
+		return err
+	}
+
+	if upToDate {
+		w.logger.Info("Vault is up to date", "vault", w.name)
+	} else {
+		w.rebuild()
+	}
+
+	ticks := w.ticks
+	if ticks == nil {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+	now := w.now
+	if now == nil {
+		now = time.Now
+	}
+
+	pending := false
+	var lastChange time.Time
+	for range ticks {
+		cur, err := inputcache.Scan(w.inputs.Paths(), w.inputs, w.ignored)
+		if err != nil {
+			w.logger.Error("Error checking for changes", "error", err)
+			continue
+		}
+
+		changed := inputcache.Changed(w.inputs, cur)
+		w.inputs = cur
+		if len(changed) > 0 {
+			for _, inputPath := range changed {
+				w.logger.Info("Input changed", "path", inputPath)
+			}
+			pending = true
+			lastChange = now()
+		}
+
+		// Wait for changes to stop before rebuilding so a burst of changes, such as
+		// a frontend build writing many files, only causes a single rebuild.
+		if pending && now().Sub(lastChange) >= w.debounce {
+			pending = false
+			w.rebuild()
+		}
+	}
+
+	return nil
+}
+
+// loadCache reads the cache file and reports whether the vault's outputs exist and
+// none of its inputs have changed since it was last built.
+func (w *vaultWatcher) loadCache() (bool, error) {
+	c, err := inputcache.Load(w.cachePath)
+	if err != nil {
+		w.logger.Warn("Ignoring invalid cache file", "path", w.cachePath, "error", err)
+		return false, nil
+	}
+	if c.Key != w.key || len(c.Inputs) == 0 {
+		return false, nil
+	}
+
+	for _, out := range w.outputs {
+		if _, err := os.Stat(out); err != nil {
+			return false, nil
+		}
+	}
+
+	w.inputs, err = inputcache.Scan(c.Inputs.Paths(), c.Inputs, w.ignored)
+	if err != nil {
+		return false, err
+	}
+
+	return len(inputcache.Changed(c.Inputs, w.inputs)) == 0, nil
+}
+
+// ignored reports whether the path is one the watcher writes itself, an output file,
+// the cache file or a temporary file used to write one of them. Writing them
+// shouldn't cause a rebuild, even when they're in an included directory.
+func (w *vaultWatcher) ignored(inputPath string) bool {
+	absPath, err := filepath.Abs(inputPath)
+	if err != nil {
+		return false
+	}
+
+	for _, written := range append([]string{w.cachePath}, w.outputs...) {
+		absWritten, err := filepath.Abs(written)
+		if err != nil {
+			continue
+		}
+		if absPath == absWritten || atomicfile.IsTempFile(absWritten, absPath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rebuild builds and writes the vault, then records the state of its inputs. Errors
+// are logged rather than returned so watching continues until the problem is fixed.
+func (w *vaultWatcher) rebuild() {
+	start := time.Now()
+	w.logger.Info("Building vault", "vault", w.name)
+
+	b, err := w.build()
+	if err == nil {
+		err = w.write(b)
+	}
+
+	inputPaths := b.Inputs()
+	if err != nil {
+		// Keep watching the previous inputs too, since a failed build may not have
+		// read all of them.
+		inputPaths = append(inputPaths, w.inputs.Paths()...)
+	}
+
+	inputs, scanErr := inputcache.Scan(inputPaths, w.inputs, w.ignored)
+	if scanErr != nil {
+		w.logger.Error("Error checking for changes", "error", scanErr)
+		return
+	}
+	// Files written while the build was reading them are rebuilt again.
+	inputs.Forget(start)
+	w.inputs = inputs
+
+	if err != nil {
+		w.logger.Error("Error building vault", "vault", w.name, "error", err)
+		return
+	}
+
+	err = (&inputcache.Cache{Key: w.key, Inputs: inputs}).Save(w.cachePath)
+	if err != nil {
+		w.logger.Warn("Error writing cache file", "path", w.cachePath, "error", err)
+	}
+
+	w.logger.Info("Built vault", "vault", w.name, "elapsed", time.Since(start).Round(time.Millisecond))
+}
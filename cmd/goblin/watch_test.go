@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aphistic/goblin"
+	"github.com/aphistic/goblin/internal/atomicfile"
+)
+
+func TestWatchCacheKey(t *testing.T) {
+	modTime := time.Date(2020, 4, 8, 0, 0, 0, 0, time.UTC)
+	key := watchCacheKey([]string{"watch", "--name", "assets"}, modTime)
+
+	assert.Equal(t, key, watchCacheKey([]string{"watch", "--name", "assets"}, modTime))
+	assert.NotEqual(t, key, watchCacheKey([]string{"watch", "--name", "other"}, modTime))
+	assert.NotEqual(t, key, watchCacheKey([]string{"watch", "--name", "assets", "--precompress"}, modTime))
+	assert.NotEqual(t, key, watchCacheKey([]string{"watch", "--name", "assets"}, modTime.Add(time.Second)))
+	// Arguments are separated so moving text between them changes the key.
+	assert.NotEqual(t, key, watchCacheKey([]string{"watch", "--name assets"}, modTime))
+}
+
+// testWatcher runs a vault watcher for a vault built from the files in a temp dir,
+// with the output and cache written inside the same dir, and a fake ticker and clock.
+type testWatcher struct {
+	t *testing.T
+	w *vaultWatcher
+
+	ticks  chan time.Time
+	result chan error
+
+	lock   sync.Mutex
+	now    time.Time
+	builds int
+}
+
+func newTestWatcher(t *testing.T, srcDir string, key string) *testWatcher {
+	tw := &testWatcher{
+		t:      t,
+		ticks:  make(chan time.Time),
+		now:    time.Date(2020, 4, 8, 0, 0, 0, 0, time.UTC),
+		result: make(chan error, 1),
+	}
+
+	out := filepath.Join(srcDir, "out.go")
+	tw.w = &vaultWatcher{
+		name:   "assets",
+		logger: logger,
+		build: func() (*goblin.MemoryBuilder, error) {
+			tw.lock.Lock()
+			tw.builds++
+			tw.lock.Unlock()
+
+			b := goblin.NewMemoryBuilder(goblin.MemoryBuilderExclude("out.go", ".out.go.cache"))
+			err := b.IncludeDirs(srcDir, []string{"."})
+			if err != nil {
+				return b, err
+			}
+
+			data, err := ioutil.ReadFile(filepath.Join(srcDir, "a.txt"))
+			if err != nil {
+				return b, err
+			}
+			if bytes.Contains(data, []byte("bad")) {
+				return b, errors.New("bad input")
+			}
+
+			return b, nil
+		},
+		write: func(b *goblin.MemoryBuilder) error {
+			return atomicfile.WriteFile(out, 0644, func(w io.Writer) error {
+				_, err := w.Write([]byte("package assets\n"))
+				return err
+			})
+		},
+		outputs:   []string{out},
+		cachePath: filepath.Join(srcDir, ".out.go.cache"),
+		key:       key,
+		debounce:  time.Second,
+		ticks:     tw.ticks,
+		now: func() time.Time {
+			tw.lock.Lock()
+			defer tw.lock.Unlock()
+
+			return tw.now
+		},
+	}
+
+	return tw
+}
+
+func (tw *testWatcher) start() {
+	go func() {
+		tw.result <- tw.w.run()
+	}()
+}
+
+// tick sends two ticks so the watcher has finished handling the first one when it
+// returns. The second tick doesn't see any new changes unless the test makes them.
+func (tw *testWatcher) tick() {
+	tw.ticks <- time.Time{}
+	tw.ticks <- time.Time{}
+}
+
+// advance moves the watcher's clock forward.
+func (tw *testWatcher) advance(d time.Duration) {
+	tw.lock.Lock()
+	defer tw.lock.Unlock()
+
+	tw.now = tw.now.Add(d)
+}
+
+func (tw *testWatcher) stop() {
+	close(tw.ticks)
+	require.NoError(tw.t, <-tw.result)
+}
+
+func (tw *testWatcher) buildCount() int {
+	tw.lock.Lock()
+	defer tw.lock.Unlock()
+
+	return tw.builds
+}
+
+func newWatchTestDir(t *testing.T) string {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(td)
+	})
+
+	writeTestFiles(t, td, map[string]string{
+		"a.txt": "a",
+	})
+
+	return td
+}
+
+func TestVaultWatcher(t *testing.T) {
+	t.Run("debounce", func(t *testing.T) {
+		useTestLogger(t)
+		td := newWatchTestDir(t)
+
+		tw := newTestWatcher(t, td, "key")
+		tw.start()
+		defer tw.stop()
+
+		// Wait for the initial build to finish.
+		tw.tick()
+		require.Equal(t, 1, tw.buildCount())
+		assert.FileExists(t, filepath.Join(td, "out.go"))
+		assert.FileExists(t, filepath.Join(td, ".out.go.cache"))
+
+		// Writing the output and cache in the watched dir doesn't cause a rebuild.
+		tw.tick()
+		assert.Equal(t, 1, tw.buildCount())
+
+		// Neither does a temp file left while writing the output.
+		writeTestFiles(t, td, map[string]string{".out.go.tmp123": "package"})
+		tw.tick()
+		tw.advance(2 * time.Second)
+		tw.tick()
+		assert.Equal(t, 1, tw.buildCount())
+
+		writeTestFiles(t, td, map[string]string{"a.txt": "changed"})
+		tw.tick()
+		assert.Equal(t, 1, tw.buildCount())
+
+		tw.advance(500 * time.Millisecond)
+		writeTestFiles(t, td, map[string]string{"b.txt": "new"})
+		tw.tick()
+		assert.Equal(t, 1, tw.buildCount())
+
+		// The debounce waits from the last change, not the first.
+		tw.advance(800 * time.Millisecond)
+		tw.tick()
+		assert.Equal(t, 1, tw.buildCount())
+
+		tw.advance(200 * time.Millisecond)
+		tw.tick()
+		assert.Equal(t, 2, tw.buildCount())
+
+		// Rebuilding doesn't cause another rebuild.
+		tw.advance(2 * time.Second)
+		tw.tick()
+		assert.Equal(t, 2, tw.buildCount())
+	})
+
+	t.Run("rebuild failed", func(t *testing.T) {
+		logBuf := useTestLogger(t)
+		td := newWatchTestDir(t)
+		writeTestFiles(t, td, map[string]string{"a.txt": "bad"})
+
+		tw := newTestWatcher(t, td, "key")
+		tw.start()
+		defer tw.stop()
+
+		tw.tick()
+		require.Equal(t, 1, tw.buildCount())
+		assert.Contains(t, logBuf.String(), "Error building vault")
+		assert.NoFileExists(t, filepath.Join(td, "out.go"))
+
+		// Nothing changed, so the failed build isn't retried.
+		tw.advance(2 * time.Second)
+		tw.tick()
+		assert.Equal(t, 1, tw.buildCount())
+
+		writeTestFiles(t, td, map[string]string{"a.txt": "fixed"})
+		tw.tick()
+		tw.advance(2 * time.Second)
+		tw.tick()
+		assert.Equal(t, 2, tw.buildCount())
+		assert.FileExists(t, filepath.Join(td, "out.go"))
+	})
+
+	t.Run("cache reuse", func(t *testing.T) {
+		logBuf := useTestLogger(t)
+		td := newWatchTestDir(t)
+
+		tw := newTestWatcher(t, td, "key")
+		tw.start()
+		tw.tick()
+		tw.stop()
+		require.Equal(t, 1, tw.buildCount())
+
+		logBuf.Reset()
+		tw = newTestWatcher(t, td, "key")
+		tw.start()
+		tw.tick()
+		tw.stop()
+		assert.Equal(t, 0, tw.buildCount())
+		assert.Contains(t, logBuf.String(), "Vault is up to date")
+
+		// Changes made while the watcher wasn't running are built when it starts.
+		writeTestFiles(t, td, map[string]string{"a.txt": "changed"})
+		tw = newTestWatcher(t, td, "key")
+		tw.start()
+		tw.tick()
+		tw.stop()
+		assert.Equal(t, 1, tw.buildCount())
+
+		tw = newTestWatcher(t, td, "other key")
+		tw.start()
+		tw.tick()
+		tw.stop()
+		assert.Equal(t, 1, tw.buildCount())
+	})
+}
@@ -0,0 +1,189 @@
+package goblin
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"mime"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// EncodingGzip is the content encoding for gzip compressed file variants.
+	EncodingGzip = "gzip"
+	// EncodingDeflate is the content encoding for deflate (zlib) compressed file
+	// variants, as defined for the HTTP Content-Encoding header.
+	EncodingDeflate = "deflate"
+)
+
+// EncodedVault is a vault that can store encoded variants of files, such as
+// precompressed versions, alongside the original file. Variants do not appear as
+// separate files when listing or globbing the vault.
+type EncodedVault interface {
+	Vault
+
+	// FileEncodings returns the encodings available for the file at the given path,
+	// not including the original unencoded file.
+	FileEncodings(name string) ([]string, error)
+	// ReadFileEncoding returns the contents of the variant of the file at the given
+	// path with the provided encoding.
+	ReadFileEncoding(name string, encoding string) ([]byte, error)
+}
+
+// preferredEncodings is the order encodings are preferred in when a client accepts
+// more than one with the same quality.
+var preferredEncodings = []string{EncodingGzip, EncodingDeflate}
+
+// preferEncodings sorts the provided encodings so preferred encodings come first.
+// Unknown encodings are kept in their original order after known encodings.
+func preferEncodings(encodings []string) []string {
+	rank := func(encoding string) int {
+		for idx, preferred := range preferredEncodings {
+			if encoding == preferred {
+				return idx
+			}
+		}
+		return len(preferredEncodings)
+	}
+
+	res := make([]string, len(encodings))
+	copy(res, encodings)
+	sort.SliceStable(res, func(i, j int) bool {
+		return rank(res[i]) < rank(res[j])
+	})
+
+	return res
+}
+
+// encodeData encodes data using the provided content encoding.
+func encodeData(encoding string, data []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+
+	switch encoding {
+	case EncodingGzip:
+		w, err := gzip.NewWriterLevel(buf, gzip.BestCompression)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	case EncodingDeflate:
+		w, err := zlib.NewWriterLevel(buf, zlib.BestCompression)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encoding: %s", encoding)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// isCompressible reports whether a file is likely to benefit from compression based
+// on its extension. Formats that are already compressed, such as images, fonts and
+// archives, are not considered compressible.
+func isCompressible(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	switch ext {
+	case ".js", ".mjs", ".css", ".html", ".htm", ".json", ".map", ".svg", ".xml",
+		".txt", ".md", ".csv", ".wasm", ".ico", ".ttf", ".otf", ".eot":
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(mime.TypeByExtension(ext))
+	if err != nil {
+		return false
+	}
+
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "+xml") ||
+		strings.HasSuffix(mediaType, "+json")
+}
+
+// negotiateEncoding picks the best encoding from those available based on the
+// value of an Accept-Encoding header. Encodings with a higher quality value are
+// preferred, and ties are broken using the order of the available encodings. An
+// empty string is returned if the unencoded file should be used.
+func negotiateEncoding(acceptEncoding string, available []string) string {
+	if acceptEncoding == "" || len(available) == 0 {
+		return ""
+	}
+
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		if coding == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+
+			q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			if err == nil {
+				quality = q
+			}
+		}
+
+		qualities[coding] = quality
+	}
+
+	type candidate struct {
+		encoding string
+		quality  float64
+		order    int
+	}
+
+	var candidates []candidate
+	for idx, encoding := range available {
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality, ok = qualities["*"]
+		}
+		if !ok || quality <= 0 {
+			continue
+		}
+
+		candidates = append(candidates, candidate{
+			encoding: encoding,
+			quality:  quality,
+			order:    idx,
+		})
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].quality == candidates[j].quality {
+			return candidates[i].order < candidates[j].order
+		}
+		return candidates[i].quality > candidates[j].quality
+	})
+
+	best := candidates[0]
+	if identityQuality, ok := qualities["identity"]; ok && identityQuality > best.quality {
+		return ""
+	}
+
+	return best.encoding
+}
@@ -0,0 +1,91 @@
+package goblin
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeData(t *testing.T) {
+	data := bytes.Repeat([]byte("goblin "), 100)
+
+	t.Run("gzip", func(t *testing.T) {
+		encData, err := encodeData(EncodingGzip, data)
+		require.NoError(t, err)
+
+		r, err := gzip.NewReader(bytes.NewReader(encData))
+		require.NoError(t, err)
+		decData, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, data, decData)
+	})
+
+	t.Run("deflate", func(t *testing.T) {
+		encData, err := encodeData(EncodingDeflate, data)
+		require.NoError(t, err)
+
+		r, err := zlib.NewReader(bytes.NewReader(encData))
+		require.NoError(t, err)
+		decData, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, data, decData)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		_, err := encodeData("br", data)
+		assert.EqualError(t, err, "unsupported encoding: br")
+	})
+}
+
+func TestIsCompressible(t *testing.T) {
+	for _, name := range []string{"app.js", "static/app.CSS", "index.html", "data.json", "logo.svg"} {
+		assert.True(t, isCompressible(name), name)
+	}
+	for _, name := range []string{"logo.png", "photo.jpg", "font.woff2", "archive.zip", "noext"} {
+		assert.False(t, isCompressible(name), name)
+	}
+}
+
+func TestPreferEncodings(t *testing.T) {
+	assert.Equal(t,
+		[]string{EncodingGzip, EncodingDeflate, "br"},
+		preferEncodings([]string{"br", EncodingDeflate, EncodingGzip}),
+	)
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	available := []string{EncodingGzip, EncodingDeflate}
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		expected       string
+	}{
+		{"no header", "", ""},
+		{"gzip", "gzip", EncodingGzip},
+		{"deflate", "deflate", EncodingDeflate},
+		{"server preference on tie", "deflate, gzip", EncodingGzip},
+		{"quality values", "gzip;q=0.5, deflate;q=0.8", EncodingDeflate},
+		{"refused", "gzip;q=0", ""},
+		{"wildcard", "*", EncodingGzip},
+		{"wildcard with refusal", "gzip;q=0, *;q=0.5", EncodingDeflate},
+		{"unsupported", "br", ""},
+		{"identity preferred", "identity;q=1, gzip;q=0.5", ""},
+		{"case insensitive", "GZIP", EncodingGzip},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, negotiateEncoding(test.acceptEncoding, available))
+		})
+	}
+
+	t.Run("no variants", func(t *testing.T) {
+		assert.Equal(t, "", negotiateEncoding("gzip", nil))
+	})
+}
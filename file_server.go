@@ -0,0 +1,338 @@
+package goblin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"html/template"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	defaultIndexFile = "index.html"
+)
+
+// FileServerOption is an option used when creating a file server.
+type FileServerOption func(*FileServer)
+
+// FileServerCacheControl sets the Cache-Control header to the provided value for
+// files with a path matching the glob. Globs without a path separator are matched
+// against the file's name, so "*.js" matches JavaScript files in any directory.
+// Rules are checked in the order they're added and the first match is used.
+func FileServerCacheControl(glob string, value string) FileServerOption {
+	return func(fs *FileServer) {
+		fs.cacheControl = append(fs.cacheControl, cacheControlRule{
+			glob:  glob,
+			value: value,
+		})
+	}
+}
+
+// FileServerIndexFile sets the name of the file served when a directory is
+// requested. The default is index.html.
+func FileServerIndexFile(name string) FileServerOption {
+	return func(fs *FileServer) {
+		fs.indexFile = name
+	}
+}
+
+// FileServerSPA enables single-page application mode. Requests for paths that don't
+// exist in the vault and don't have a file extension are served the provided fallback
+// file, usually index.html, so a client-side router can handle them. Requests for
+// missing paths with an extension, such as a missing script, still return a 404.
+func FileServerSPA(fallback string) FileServerOption {
+	return func(fs *FileServer) {
+		fs.spaFallback = strings.TrimPrefix(fallback, pathSeparator)
+	}
+}
+
+// FileServerSPAExclude prevents requests with a path under any of the provided URL
+// path prefixes, such as "/api", from being served the single-page application
+// fallback file.
+func FileServerSPAExclude(prefixes ...string) FileServerOption {
+	return func(fs *FileServer) {
+		fs.spaExclude = append(fs.spaExclude, prefixes...)
+	}
+}
+
+// FileServerSPAPrerendered causes single-page application mode to serve a
+// pre-rendered "<path>/index.html" file for a request, if one exists, before
+// falling back to the fallback file. Pre-rendered files are served without
+// redirecting to the path with a trailing slash.
+func FileServerSPAPrerendered() FileServerOption {
+	return func(fs *FileServer) {
+		fs.spaPrerendered = true
+	}
+}
+
+type cacheControlRule struct {
+	glob  string
+	value string
+}
+
+// FileServer is an http.Handler that serves files from a vault.
+//
+// Responses include a Content-Type based on the file extension (or the file's
+// contents if the extension is unknown), a Last-Modified header from the file's
+// modified time and a strong ETag based on a hash of the file's contents.
+// Conditional requests using If-None-Match or If-Modified-Since and Range
+// requests are supported.
+//
+// If the vault implements EncodedVault and has precompressed variants of a file,
+// the best variant for the request's Accept-Encoding header is served with the
+// matching Content-Encoding.
+type FileServer struct {
+	v            Vault
+	indexFile    string
+	cacheControl []cacheControlRule
+
+	spaFallback    string
+	spaExclude     []string
+	spaPrerendered bool
+
+	dirListing         bool
+	dirListingTemplate *template.Template
+}
+
+var _ http.Handler = &FileServer{}
+
+// NewFileServer creates a new file server serving files from the provided vault.
+func NewFileServer(v Vault, opts ...FileServerOption) *FileServer {
+	fs := &FileServer{
+		v:         v,
+		indexFile: defaultIndexFile,
+	}
+
+	for _, opt := range opts {
+		opt(fs)
+	}
+
+	return fs
+}
+
+func (fs *FileServer) String() string {
+	return `File Server (` + fs.v.String() + `)`
+}
+
+// ServeHTTP serves the file in the vault at the request's URL path.
+func (fs *FileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := requestVaultPath(r)
+
+	fInfo, err := fs.v.Stat(name)
+	if err != nil {
+		if os.IsNotExist(err) && fs.useSPAFallback(r) {
+			fs.serveSPA(w, r, name)
+			return
+		}
+
+		fs.serveError(w, err)
+		return
+	}
+
+	if fInfo.IsDir() {
+		// Redirect to the path with a trailing slash so relative links in
+		// the index file work as expected.
+		if !strings.HasSuffix(r.URL.Path, pathSeparator) && !fs.useSPAPrerendered(r) {
+			redirectPath := path.Base(r.URL.Path) + pathSeparator
+			if r.URL.RawQuery != "" {
+				redirectPath += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, redirectPath, http.StatusMovedPermanently)
+			return
+		}
+
+		indexName := joinVaultPath(name, fs.indexFile)
+		fInfo, err = fs.v.Stat(indexName)
+		if err != nil || fInfo.IsDir() {
+			if fs.dirListing {
+				fs.serveDirListing(w, r, name)
+				return
+			}
+			if fs.useSPAFallback(r) {
+				fs.serveSPA(w, r, name)
+				return
+			}
+
+			fs.serveError(w, os.ErrNotExist)
+			return
+		}
+		name = indexName
+	}
+
+	fs.serveFile(w, r, name, fInfo)
+}
+
+// useSPAFallback reports whether the single-page application fallback file should
+// be served for a request that doesn't match a file in the vault.
+func (fs *FileServer) useSPAFallback(r *http.Request) bool {
+	if fs.spaFallback == "" {
+		return false
+	}
+
+	urlPath := path.Clean(pathSeparator + r.URL.Path)
+	if path.Ext(urlPath) != "" {
+		return false
+	}
+
+	for _, prefix := range fs.spaExclude {
+		prefix = path.Clean(pathSeparator + prefix)
+		if urlPath == prefix || strings.HasPrefix(urlPath, strings.TrimSuffix(prefix, pathSeparator)+pathSeparator) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (fs *FileServer) useSPAPrerendered(r *http.Request) bool {
+	return fs.spaPrerendered && fs.useSPAFallback(r)
+}
+
+// serveSPA serves a pre-rendered index file for the path if enabled and available,
+// otherwise the single-page application fallback file.
+func (fs *FileServer) serveSPA(w http.ResponseWriter, r *http.Request, name string) {
+	if fs.spaPrerendered {
+		indexName := joinVaultPath(name, fs.indexFile)
+		fInfo, err := fs.v.Stat(indexName)
+		if err == nil && !fInfo.IsDir() {
+			fs.serveFile(w, r, indexName, fInfo)
+			return
+		}
+	}
+
+	fInfo, err := fs.v.Stat(fs.spaFallback)
+	if err != nil {
+		fs.serveError(w, err)
+		return
+	}
+	if fInfo.IsDir() {
+		fs.serveError(w, os.ErrNotExist)
+		return
+	}
+
+	fs.serveFile(w, r, fs.spaFallback, fInfo)
+}
+
+func (fs *FileServer) serveFile(w http.ResponseWriter, r *http.Request, name string, fInfo os.FileInfo) {
+	data, err := fs.v.ReadFile(name)
+	if err != nil {
+		fs.serveError(w, err)
+		return
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", contentType)
+	if cacheControl := fs.cacheControlFor(name); cacheControl != "" {
+		h.Set("Cache-Control", cacheControl)
+	}
+
+	encoding, encData, err := fs.selectEncoding(w, r, name)
+	if err != nil {
+		fs.serveError(w, err)
+		return
+	}
+	if encoding != "" {
+		data = encData
+		h.Set("Content-Encoding", encoding)
+	}
+
+	h.Set("ETag", contentETag(data))
+
+	http.ServeContent(w, r, name, fInfo.ModTime(), bytes.NewReader(data))
+}
+
+// selectEncoding picks the best encoded variant of the file for the request if the
+// vault supports encoded variants. If the unencoded file should be served, the
+// returned encoding is empty.
+func (fs *FileServer) selectEncoding(
+	w http.ResponseWriter, r *http.Request, name string,
+) (string, []byte, error) {
+	ev, ok := fs.v.(EncodedVault)
+	if !ok {
+		return "", nil, nil
+	}
+
+	encodings, err := ev.FileEncodings(name)
+	if err != nil || len(encodings) == 0 {
+		return "", nil, nil
+	}
+
+	// The response depends on the request's Accept-Encoding when variants are
+	// available, even if the unencoded file is served.
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), preferEncodings(encodings))
+	if encoding == "" {
+		return "", nil, nil
+	}
+
+	data, err := ev.ReadFileEncoding(name, encoding)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return encoding, data, nil
+}
+
+func (fs *FileServer) serveError(w http.ResponseWriter, err error) {
+	switch {
+	case os.IsNotExist(err):
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	case os.IsPermission(err):
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	default:
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// contentETag returns the strong ETag for the served data. The data is hashed on
+// every request because a file's modified time and size don't reliably change when
+// its contents do, such as a file written to a MemoryVault without a modified time.
+func contentETag(data []byte) string {
+	hash := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(hash[:16]) + `"`
+}
+
+func (fs *FileServer) cacheControlFor(name string) string {
+	for _, rule := range fs.cacheControl {
+		match, err := matchPathGlob(rule.glob, name)
+		if err == nil && match {
+			return rule.value
+		}
+	}
+
+	return ""
+}
+
+// requestVaultPath converts the URL path of a request into a vault path.
+func requestVaultPath(r *http.Request) string {
+	name := strings.TrimPrefix(path.Clean(pathSeparator+r.URL.Path), pathSeparator)
+	if name == "" {
+		return filesystemRootPath
+	}
+
+	return name
+}
+
+func joinVaultPath(dir string, name string) string {
+	if dir == filesystemRootPath {
+		return name
+	}
+
+	return dir + pathSeparator + name
+}
@@ -0,0 +1,39 @@
+package goblin_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/aphistic/goblin"
+)
+
+func ExampleFileServer() {
+	mVault := goblin.NewMemoryVault()
+	_ = mVault.WriteFile(
+		"static/app.js", strings.NewReader("console.log('hello');"),
+		goblin.FileModTime(time.Date(2020, 4, 8, 0, 0, 0, 0, time.UTC)),
+	)
+
+	// Serve the vault with far-future caching for static assets.
+	fileServer := goblin.NewFileServer(
+		mVault,
+		goblin.FileServerCacheControl("static/*", "public, max-age=31536000"),
+	)
+
+	rec := httptest.NewRecorder()
+	fileServer.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/app.js", nil))
+
+	fmt.Printf("Status: %d\n", rec.Code)
+	fmt.Printf("Last-Modified: %s\n", rec.Header().Get("Last-Modified"))
+	fmt.Printf("Cache-Control: %s\n", rec.Header().Get("Cache-Control"))
+	fmt.Printf("Body: %s\n", rec.Body)
+
+	// Output:
+	// Status: 200
+	// Last-Modified: Wed, 08 Apr 2020 00:00:00 GMT
+	// Cache-Control: public, max-age=31536000
+	// Body: console.log('hello');
+}
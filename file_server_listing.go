@@ -0,0 +1,155 @@
+package goblin
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// DefaultDirListingTemplate is the template used to render directory listings when
+// a custom template isn't provided with FileServerDirListingTemplate.
+var DefaultDirListingTemplate = template.Must(template.New("listing").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Index of {{ .Path }}</title>
+</head>
+<body>
+<h1>Index of {{ .Path }}</h1>
+<table>
+<thead><tr><th>Name</th><th>Size</th><th>Modified</th></tr></thead>
+<tbody>
+{{- if ne .Path "/" }}
+<tr><td><a href="../">../</a></td><td></td><td></td></tr>
+{{- end }}
+{{- range .Entries }}
+<tr><td><a href="{{ .URL }}">{{ .Name }}{{ if .IsDir }}/{{ end }}</a></td><td>{{ if not .IsDir }}{{ .HumanSize }}{{ end }}</td><td>{{ .ModTime.UTC.Format "2006-01-02 15:04:05" }}</td></tr>
+{{- end }}
+</tbody>
+</table>
+</body>
+</html>
+`))
+
+// FileServerDirListing enables directory listings for directories that don't contain
+// an index file. Listings are rendered as HTML by default, or as JSON if the request
+// has a "format=json" query parameter or only accepts application/json. Directory
+// listings are disabled by default.
+func FileServerDirListing() FileServerOption {
+	return func(fs *FileServer) {
+		fs.dirListing = true
+	}
+}
+
+// FileServerDirListingTemplate enables directory listings and renders HTML listings
+// with the provided template. The template is executed with a DirListing value.
+func FileServerDirListingTemplate(tmpl *template.Template) FileServerOption {
+	return func(fs *FileServer) {
+		fs.dirListing = true
+		fs.dirListingTemplate = tmpl
+	}
+}
+
+// DirListing is the contents of a directory rendered by a FileServer.
+type DirListing struct {
+	// Path is the URL path of the directory, always ending with a slash.
+	Path    string            `json:"path"`
+	Entries []DirListingEntry `json:"entries"`
+}
+
+// DirListingEntry is a single file or directory in a DirListing.
+type DirListingEntry struct {
+	Name  string `json:"name"`
+	IsDir bool   `json:"is_dir"`
+	// URL is the URL of the entry relative to the directory.
+	URL  string `json:"url"`
+	Size int64  `json:"size"`
+	// HumanSize is the size of the entry in a human readable format, such as 1.2 MB.
+	HumanSize string    `json:"-"`
+	ModTime   time.Time `json:"mod_time"`
+}
+
+func (fs *FileServer) serveDirListing(w http.ResponseWriter, r *http.Request, name string) {
+	infos, err := fs.v.ReadDir(name)
+	if err != nil {
+		fs.serveError(w, err)
+		return
+	}
+
+	listing := DirListing{
+		Path:    pathSeparator,
+		Entries: []DirListingEntry{},
+	}
+	if name != filesystemRootPath {
+		listing.Path += name + pathSeparator
+	}
+	for _, info := range infos {
+		entryURL := url.PathEscape(info.Name())
+		if info.IsDir() {
+			entryURL += pathSeparator
+		}
+
+		listing.Entries = append(listing.Entries, DirListingEntry{
+			Name:      info.Name(),
+			IsDir:     info.IsDir(),
+			URL:       entryURL,
+			Size:      info.Size(),
+			HumanSize: humanize.Bytes(uint64(info.Size())),
+			ModTime:   info.ModTime(),
+		})
+	}
+
+	if wantsJSONListing(r) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(listing)
+		return
+	}
+
+	tmpl := fs.dirListingTemplate
+	if tmpl == nil {
+		tmpl = DefaultDirListingTemplate
+	}
+
+	// Render the listing before writing anything so a template error can still be
+	// reported with an error status.
+	buf := bytes.NewBuffer(nil)
+	err = tmpl.Execute(buf, listing)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = buf.WriteTo(w)
+}
+
+// wantsJSONListing reports whether the request asked for a JSON directory listing.
+func wantsJSONListing(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "json" {
+		return true
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return false
+	}
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if mediaType != "application/json" {
+			return false
+		}
+	}
+
+	return true
+}
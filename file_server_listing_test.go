@@ -0,0 +1,129 @@
+package goblin
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestListingVault() *MemoryVault {
+	v := NewMemoryVault()
+	_ = v.WriteFile("logs/app.log", bytes.NewBufferString(strings.Repeat("a", 2000)),
+		FileModTime(testFileServerModTime))
+	_ = v.WriteFile("logs/archive/old.log", bytes.NewBufferString("old"),
+		FileModTime(testFileServerModTime))
+	_ = v.WriteFile("logs/a b.txt", bytes.NewBufferString("spaces"),
+		FileModTime(testFileServerModTime))
+	return v
+}
+
+func TestFileServerDirListing(t *testing.T) {
+	t.Run("disabled by default", func(t *testing.T) {
+		fs := NewFileServer(newTestListingVault())
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/logs/", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("html listing", func(t *testing.T) {
+		fs := NewFileServer(newTestListingVault(), FileServerDirListing())
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/logs/", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+
+		body := rec.Body.String()
+		assert.Contains(t, body, "<title>Index of /logs/</title>")
+		assert.Contains(t, body, `<a href="../">../</a>`)
+		assert.Contains(t, body, `<a href="a%20b.txt">a b.txt</a>`)
+		assert.Contains(t, body, `<a href="app.log">app.log</a></td><td>2.0 kB</td><td>2020-04-08 00:00:00</td>`)
+		assert.Contains(t, body, `<a href="archive/">archive/</a>`)
+	})
+
+	t.Run("root listing", func(t *testing.T) {
+		fs := NewFileServer(newTestListingVault(), FileServerDirListing())
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		body := rec.Body.String()
+		assert.Contains(t, body, "<title>Index of /</title>")
+		assert.NotContains(t, body, `<a href="../">`)
+	})
+
+	t.Run("index file is preferred", func(t *testing.T) {
+		v := newTestListingVault()
+		_ = v.WriteFile("logs/index.html", bytes.NewBufferString("index"))
+
+		fs := NewFileServer(v, FileServerDirListing())
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/logs/", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "index", rec.Body.String())
+	})
+
+	t.Run("json listing", func(t *testing.T) {
+		fs := NewFileServer(newTestListingVault(), FileServerDirListing())
+
+		for _, r := range []*http.Request{
+			httptest.NewRequest(http.MethodGet, "/logs/?format=json", nil),
+			func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/logs/", nil)
+				r.Header.Set("Accept", "application/json")
+				return r
+			}(),
+		} {
+			rec := serveTestRequest(fs, r)
+			require.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+			var listing DirListing
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listing))
+			assert.Equal(t, "/logs/", listing.Path)
+			require.Len(t, listing.Entries, 3)
+			assert.Equal(t, "a b.txt", listing.Entries[0].Name)
+			assert.Equal(t, "app.log", listing.Entries[1].Name)
+			assert.Equal(t, int64(2000), listing.Entries[1].Size)
+			assert.True(t, listing.Entries[1].ModTime.Equal(testFileServerModTime))
+			assert.Equal(t, "archive/", listing.Entries[2].URL)
+			assert.True(t, listing.Entries[2].IsDir)
+		}
+	})
+
+	t.Run("browser accept header gets html", func(t *testing.T) {
+		fs := NewFileServer(newTestListingVault(), FileServerDirListing())
+		r := httptest.NewRequest(http.MethodGet, "/logs/", nil)
+		r.Header.Set("Accept", "text/html,application/xhtml+xml,application/json;q=0.9")
+		rec := serveTestRequest(fs, r)
+
+		assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("custom template", func(t *testing.T) {
+		tmpl := template.Must(template.New("custom").Parse(
+			`{{ range .Entries }}{{ .Name }}={{ .HumanSize }};{{ end }}`,
+		))
+		fs := NewFileServer(newTestListingVault(), FileServerDirListingTemplate(tmpl))
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/logs/", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "a b.txt=6 B;app.log=2.0 kB;archive=0 B;", rec.Body.String())
+	})
+
+	t.Run("template error", func(t *testing.T) {
+		tmpl := template.Must(template.New("broken").Parse(
+			`{{ .Path }}{{ index .Entries 100 }}`,
+		))
+		fs := NewFileServer(newTestListingVault(), FileServerDirListingTemplate(tmpl))
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/logs/", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "Internal Server Error\n", rec.Body.String())
+	})
+}
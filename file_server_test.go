@@ -0,0 +1,389 @@
+package goblin
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testFileServerModTime = time.Date(2020, 4, 8, 0, 0, 0, 0, time.UTC)
+
+func newTestFileServerVault() *MemoryVault {
+	v := NewMemoryVault()
+	_ = v.WriteFile("index.html", bytes.NewBufferString("<html>root</html>"),
+		FileModTime(testFileServerModTime))
+	_ = v.WriteFile("static/app.js", bytes.NewBufferString("console.log('app');"),
+		FileModTime(testFileServerModTime))
+	_ = v.WriteFile("static/data.unknownext", bytes.NewBufferString("plain text"),
+		FileModTime(testFileServerModTime))
+	_ = v.WriteFile("docs/index.html", bytes.NewBufferString("<html>docs</html>"),
+		FileModTime(testFileServerModTime))
+	_ = v.WriteFile("empty/.keep", bytes.NewBufferString(""),
+		FileModTime(testFileServerModTime))
+	return v
+}
+
+func serveTestRequest(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestFileServerServeFile(t *testing.T) {
+	t.Run("serve file", func(t *testing.T) {
+		fs := NewFileServer(newTestFileServerVault())
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/static/app.js", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "console.log('app');", rec.Body.String())
+		assert.Contains(t, rec.Header().Get("Content-Type"), "javascript")
+		assert.Equal(t, testFileServerModTime.Format(http.TimeFormat), rec.Header().Get("Last-Modified"))
+		assert.Regexp(t, `^"[0-9a-f]{32}"$`, rec.Header().Get("ETag"))
+		assert.Equal(t, "", rec.Header().Get("Cache-Control"))
+	})
+
+	t.Run("detect content type", func(t *testing.T) {
+		fs := NewFileServer(newTestFileServerVault())
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/static/data.unknownext", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("head request", func(t *testing.T) {
+		fs := NewFileServer(newTestFileServerVault())
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodHead, "/static/app.js", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "", rec.Body.String())
+		assert.Equal(t, "19", rec.Header().Get("Content-Length"))
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		fs := NewFileServer(newTestFileServerVault())
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodPost, "/static/app.js", nil))
+
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		assert.Equal(t, "GET, HEAD", rec.Header().Get("Allow"))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		fs := NewFileServer(newTestFileServerVault())
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/static/missing.js", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("path traversal is cleaned", func(t *testing.T) {
+		fs := NewFileServer(newTestFileServerVault())
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.URL.Path = "/../static/../static/app.js"
+		rec := serveTestRequest(fs, r)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "console.log('app');", rec.Body.String())
+	})
+}
+
+func TestFileServerDirectories(t *testing.T) {
+	t.Run("root index", func(t *testing.T) {
+		fs := NewFileServer(newTestFileServerVault())
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "<html>root</html>", rec.Body.String())
+		assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("subdirectory index", func(t *testing.T) {
+		fs := NewFileServer(newTestFileServerVault())
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/docs/", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "<html>docs</html>", rec.Body.String())
+	})
+
+	t.Run("redirect to trailing slash", func(t *testing.T) {
+		fs := NewFileServer(newTestFileServerVault())
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/docs?page=1", nil))
+
+		assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+		assert.Equal(t, "/docs/?page=1", rec.Header().Get("Location"))
+	})
+
+	t.Run("missing index", func(t *testing.T) {
+		fs := NewFileServer(newTestFileServerVault())
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/empty/", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("custom index", func(t *testing.T) {
+		fs := NewFileServer(newTestFileServerVault(), FileServerIndexFile(".keep"))
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/empty/", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestFileServerConditionalRequests(t *testing.T) {
+	t.Run("if-none-match", func(t *testing.T) {
+		fs := NewFileServer(newTestFileServerVault())
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/static/app.js", nil))
+		etag := rec.Header().Get("ETag")
+
+		r := httptest.NewRequest(http.MethodGet, "/static/app.js", nil)
+		r.Header.Set("If-None-Match", etag)
+		rec = serveTestRequest(fs, r)
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+
+		r = httptest.NewRequest(http.MethodGet, "/static/app.js", nil)
+		r.Header.Set("If-None-Match", `"other"`)
+		rec = serveTestRequest(fs, r)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("if-modified-since", func(t *testing.T) {
+		fs := NewFileServer(newTestFileServerVault())
+
+		r := httptest.NewRequest(http.MethodGet, "/static/app.js", nil)
+		r.Header.Set("If-Modified-Since", testFileServerModTime.Format(http.TimeFormat))
+		rec := serveTestRequest(fs, r)
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+
+		r = httptest.NewRequest(http.MethodGet, "/static/app.js", nil)
+		r.Header.Set("If-Modified-Since", testFileServerModTime.Add(-time.Hour).Format(http.TimeFormat))
+		rec = serveTestRequest(fs, r)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("etag changes with content", func(t *testing.T) {
+		v := newTestFileServerVault()
+		fs := NewFileServer(v)
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/static/app.js", nil))
+		etag := rec.Header().Get("ETag")
+
+		_ = v.WriteFile("static/app.js", bytes.NewBufferString("console.log('changed');"))
+		rec = serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/static/app.js", nil))
+		assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+	})
+
+	t.Run("etag changes with same size and modified time", func(t *testing.T) {
+		v := newTestFileServerVault()
+		fs := NewFileServer(v)
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/static/app.js", nil))
+		etag := rec.Header().Get("ETag")
+
+		_ = v.WriteFile("static/app.js", bytes.NewBufferString("console.log('bpp');"),
+			FileModTime(testFileServerModTime))
+		r := httptest.NewRequest(http.MethodGet, "/static/app.js", nil)
+		r.Header.Set("If-None-Match", etag)
+		rec = serveTestRequest(fs, r)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+		assert.Equal(t, "console.log('bpp');", rec.Body.String())
+	})
+
+	t.Run("etag changes with same size and modified time on disk", func(t *testing.T) {
+		td, err := ioutil.TempDir("", testTempPattern)
+		require.NoError(t, err)
+		defer os.RemoveAll(td)
+
+		filePath := path.Join(td, "app.js")
+		require.NoError(t, ioutil.WriteFile(filePath, []byte("app"), 0644))
+		require.NoError(t, os.Chtimes(filePath, testFileServerModTime, testFileServerModTime))
+
+		fs := NewFileServer(NewFilesystemVault(td))
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/app.js", nil))
+		etag := rec.Header().Get("ETag")
+
+		require.NoError(t, ioutil.WriteFile(filePath, []byte("bpp"), 0644))
+		require.NoError(t, os.Chtimes(filePath, testFileServerModTime, testFileServerModTime))
+		rec = serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/app.js", nil))
+		assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+	})
+
+	t.Run("range", func(t *testing.T) {
+		fs := NewFileServer(newTestFileServerVault())
+
+		r := httptest.NewRequest(http.MethodGet, "/static/app.js", nil)
+		r.Header.Set("Range", "bytes=0-6")
+		rec := serveTestRequest(fs, r)
+		assert.Equal(t, http.StatusPartialContent, rec.Code)
+		assert.Equal(t, "console", rec.Body.String())
+		assert.Equal(t, "bytes 0-6/19", rec.Header().Get("Content-Range"))
+	})
+}
+
+func TestFileServerCacheControl(t *testing.T) {
+	fs := NewFileServer(
+		newTestFileServerVault(),
+		FileServerCacheControl("*.html", "no-cache"),
+		FileServerCacheControl("static/*", "public, max-age=31536000, immutable"),
+	)
+
+	rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/static/app.js", nil))
+	assert.Equal(t, "public, max-age=31536000, immutable", rec.Header().Get("Cache-Control"))
+
+	rec = serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/docs/", nil))
+	assert.Equal(t, "no-cache", rec.Header().Get("Cache-Control"))
+}
+
+func TestFileServerEncodings(t *testing.T) {
+	newEncodedVault := func() *MemoryVault {
+		v := newTestFileServerVault()
+		_ = v.WriteFileEncoding("static/app.js", EncodingGzip, bytes.NewBufferString("gzipped"))
+		_ = v.WriteFileEncoding("static/app.js", EncodingDeflate, bytes.NewBufferString("deflated"))
+		return v
+	}
+
+	t.Run("serve gzip variant", func(t *testing.T) {
+		fs := NewFileServer(newEncodedVault())
+		r := httptest.NewRequest(http.MethodGet, "/static/app.js", nil)
+		r.Header.Set("Accept-Encoding", "gzip, deflate")
+		rec := serveTestRequest(fs, r)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "gzipped", rec.Body.String())
+		assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+		assert.Contains(t, rec.Header().Get("Content-Type"), "javascript")
+	})
+
+	t.Run("serve deflate variant", func(t *testing.T) {
+		fs := NewFileServer(newEncodedVault())
+		r := httptest.NewRequest(http.MethodGet, "/static/app.js", nil)
+		r.Header.Set("Accept-Encoding", "deflate")
+		rec := serveTestRequest(fs, r)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "deflated", rec.Body.String())
+		assert.Equal(t, "deflate", rec.Header().Get("Content-Encoding"))
+	})
+
+	t.Run("serve original", func(t *testing.T) {
+		fs := NewFileServer(newEncodedVault())
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/static/app.js", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "console.log('app');", rec.Body.String())
+		assert.Equal(t, "", rec.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+	})
+
+	t.Run("etags differ per encoding", func(t *testing.T) {
+		fs := NewFileServer(newEncodedVault())
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/static/app.js", nil))
+		plainETag := rec.Header().Get("ETag")
+
+		r := httptest.NewRequest(http.MethodGet, "/static/app.js", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		rec = serveTestRequest(fs, r)
+		assert.NotEqual(t, plainETag, rec.Header().Get("ETag"))
+	})
+
+	t.Run("no vary without variants", func(t *testing.T) {
+		fs := NewFileServer(newEncodedVault())
+		r := httptest.NewRequest(http.MethodGet, "/index.html", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		rec := serveTestRequest(fs, r)
+
+		assert.Equal(t, "", rec.Header().Get("Content-Encoding"))
+		assert.Equal(t, "", rec.Header().Get("Vary"))
+	})
+}
+
+func TestFileServerSPA(t *testing.T) {
+	newSPAVault := func() *MemoryVault {
+		v := newTestFileServerVault()
+		_ = v.WriteFile("about/index.html", bytes.NewBufferString("<html>about</html>"))
+		return v
+	}
+
+	t.Run("fallback for unknown route", func(t *testing.T) {
+		fs := NewFileServer(newSPAVault(), FileServerSPA("index.html"))
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/users/123", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "<html>root</html>", rec.Body.String())
+		assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("real assets are served", func(t *testing.T) {
+		fs := NewFileServer(newSPAVault(), FileServerSPA("index.html"))
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/static/app.js", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "console.log('app');", rec.Body.String())
+	})
+
+	t.Run("missing assets with extension are not found", func(t *testing.T) {
+		fs := NewFileServer(newSPAVault(), FileServerSPA("index.html"))
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/static/missing.js", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("excluded prefixes are not found", func(t *testing.T) {
+		fs := NewFileServer(
+			newSPAVault(),
+			FileServerSPA("index.html"),
+			FileServerSPAExclude("/api"),
+		)
+
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/api", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/apiary", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("directory without index", func(t *testing.T) {
+		fs := NewFileServer(newSPAVault(), FileServerSPA("index.html"))
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/empty/", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "<html>root</html>", rec.Body.String())
+	})
+
+	t.Run("pre-rendered pages", func(t *testing.T) {
+		fs := NewFileServer(
+			newSPAVault(),
+			FileServerSPA("index.html"),
+			FileServerSPAPrerendered(),
+		)
+
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/about", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "<html>about</html>", rec.Body.String())
+
+		rec = serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/users/123", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "<html>root</html>", rec.Body.String())
+	})
+
+	t.Run("without pre-rendering directories redirect", func(t *testing.T) {
+		fs := NewFileServer(newSPAVault(), FileServerSPA("index.html"))
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/about", nil))
+
+		assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	})
+
+	t.Run("missing fallback", func(t *testing.T) {
+		fs := NewFileServer(newSPAVault(), FileServerSPA("missing.html"))
+		rec := serveTestRequest(fs, httptest.NewRequest(http.MethodGet, "/users/123", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -1,6 +1,9 @@
 package goblin
 
-import "os"
+import (
+	"io"
+	"os"
+)
 
 type openFSFile struct {
 	fullPath string
@@ -8,6 +11,7 @@ type openFSFile struct {
 }
 
 var _ File = &openFSFile{}
+var _ io.Seeker = &openFSFile{}
 
 func newOpenFSFile(fullPath string, f *os.File) *openFSFile {
 	return &openFSFile{
@@ -24,6 +28,10 @@ func (off *openFSFile) Read(buf []byte) (int, error) {
 	return off.f.Read(buf)
 }
 
+func (off *openFSFile) Seek(offset int64, whence int) (int64, error) {
+	return off.f.Seek(offset, whence)
+}
+
 func (off *openFSFile) Close() error {
 	return off.f.Close()
 }
@@ -4,23 +4,58 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
+const (
+	defaultFilesystemPollInterval = time.Second
+)
+
+// FilesystemVaultOption is an option used when creating a filesystem vault.
+type FilesystemVaultOption func(*FilesystemVault)
+
+// FilesystemVaultPollInterval sets how often the filesystem is checked for changes
+// while the vault is being watched. The default is one second, which is also used if
+// the interval isn't positive.
+func FilesystemVaultPollInterval(interval time.Duration) FilesystemVaultOption {
+	return func(v *FilesystemVault) {
+		if interval <= 0 {
+			interval = defaultFilesystemPollInterval
+		}
+		v.pollInterval = interval
+	}
+}
+
 // FilesystemVault is a vault used to interact with a local filesystem. All paths
 // provided to a FilesystemVault are relative to the root path.
 type FilesystemVault struct {
-	rootPath string
+	rootPath     string
+	pollInterval time.Duration
+
+	watchers watcherSet
+	pollLock sync.Mutex
+	polling  bool
 }
 
 var _ Vault = &FilesystemVault{}
+var _ WatchVault = &FilesystemVault{}
 
 // NewFilesystemVault creates a FilesystemVault using the given root path as the
 // root of the filesystem.
-func NewFilesystemVault(rootPath string) *FilesystemVault {
-	return &FilesystemVault{
-		rootPath: rootPath,
+func NewFilesystemVault(rootPath string, opts ...FilesystemVaultOption) *FilesystemVault {
+	v := &FilesystemVault{
+		rootPath:     rootPath,
+		pollInterval: defaultFilesystemPollInterval,
+	}
+
+	for _, opt := range opts {
+		opt(v)
 	}
+
+	return v
 }
 
 func (v *FilesystemVault) String() string {
@@ -99,3 +134,108 @@ func (v *FilesystemVault) ReadFile(name string) ([]byte, error) {
 
 	return ioutil.ReadFile(fullPath)
 }
+
+// Watch returns a Watcher for files in the vault matching the provided pattern.
+//
+// Changes are detected by periodically comparing the modified time and size of
+// every file under the vault's root path, so no platform-specific notification
+// support is required. The poll interval can be changed using the
+// FilesystemVaultPollInterval option.
+func (v *FilesystemVault) Watch(pattern string) (*Watcher, error) {
+	w, err := v.watchers.Add(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	v.pollLock.Lock()
+	defer v.pollLock.Unlock()
+
+	if !v.polling {
+		v.polling = true
+		go v.poll(v.snapshot())
+	}
+
+	return w, nil
+}
+
+type fsFileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func (v *FilesystemVault) snapshot() map[string]fsFileState {
+	res := map[string]fsFileState{}
+
+	// Errors are ignored on purpose. A missing root path or an unreadable
+	// directory are treated as having no files so they'll show up as created
+	// if they become available later.
+	_ = filepath.Walk(v.rootPath, func(fullPath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(v.rootPath, fullPath)
+		if err != nil {
+			return nil
+		}
+
+		res[filepath.ToSlash(relPath)] = fsFileState{
+			modTime: info.ModTime(),
+			size:    info.Size(),
+		}
+
+		return nil
+	})
+
+	return res
+}
+
+func (v *FilesystemVault) poll(prev map[string]fsFileState) {
+	ticker := time.NewTicker(v.pollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		v.pollLock.Lock()
+		if v.watchers.Len() == 0 {
+			v.polling = false
+			v.pollLock.Unlock()
+			return
+		}
+		v.pollLock.Unlock()
+
+		cur := v.snapshot()
+		for _, evt := range diffFileStates(prev, cur) {
+			v.watchers.Send(evt)
+		}
+		prev = cur
+	}
+}
+
+func diffFileStates(prev map[string]fsFileState, cur map[string]fsFileState) []WatchEvent {
+	var res []WatchEvent
+	for name, curState := range cur {
+		prevState, ok := prev[name]
+		switch {
+		case !ok:
+			res = append(res, WatchEvent{Type: WatchEventCreate, Path: name})
+		case !prevState.modTime.Equal(curState.modTime) || prevState.size != curState.size:
+			res = append(res, WatchEvent{Type: WatchEventModify, Path: name})
+		}
+	}
+	for name := range prev {
+		if _, ok := cur[name]; !ok {
+			res = append(res, WatchEvent{Type: WatchEventRemove, Path: name})
+		}
+	}
+
+	// Keep events in a predictable order so consumers see the same sequence
+	// for the same set of changes.
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Path == res[j].Path {
+			return res[i].Type < res[j].Type
+		}
+		return res[i].Path < res[j].Path
+	})
+
+	return res
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -46,3 +47,88 @@ func TestFSVaultMakePath(t *testing.T) {
 		assert.Equal(t, td, p)
 	})
 }
+
+func TestFSVaultPollInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		expected time.Duration
+	}{
+		{name: "positive", interval: 10 * time.Millisecond, expected: 10 * time.Millisecond},
+		{name: "zero", interval: 0, expected: defaultFilesystemPollInterval},
+		{name: "negative", interval: -time.Second, expected: defaultFilesystemPollInterval},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := NewFilesystemVault(".", FilesystemVaultPollInterval(test.interval))
+			assert.Equal(t, test.expected, v.pollInterval)
+		})
+	}
+}
+
+func TestFSVaultWatch(t *testing.T) {
+	t.Run("create, modify and remove", func(t *testing.T) {
+		td, err := ioutil.TempDir("", testTempPattern)
+		require.NoError(t, err)
+		defer os.RemoveAll(td)
+
+		filePath := path.Join(td, "file.txt")
+		require.NoError(t, ioutil.WriteFile(path.Join(td, "existing.txt"), []byte("a"), 0644))
+
+		v := NewFilesystemVault(td, FilesystemVaultPollInterval(10*time.Millisecond))
+		w, err := v.Watch("*.txt")
+		require.NoError(t, err)
+		defer w.Close()
+
+		require.NoError(t, ioutil.WriteFile(filePath, []byte("a"), 0644))
+		assert.Equal(t, WatchEvent{Type: WatchEventCreate, Path: "file.txt"}, receiveWatchEvent(t, w))
+
+		require.NoError(t, ioutil.WriteFile(filePath, []byte("ab"), 0644))
+		assert.Equal(t, WatchEvent{Type: WatchEventModify, Path: "file.txt"}, receiveWatchEvent(t, w))
+
+		require.NoError(t, os.Remove(filePath))
+		assert.Equal(t, WatchEvent{Type: WatchEventRemove, Path: "file.txt"}, receiveWatchEvent(t, w))
+	})
+
+	t.Run("missing root", func(t *testing.T) {
+		td, err := ioutil.TempDir("", testTempPattern)
+		require.NoError(t, err)
+		defer os.RemoveAll(td)
+
+		rootPath := path.Join(td, "root")
+		v := NewFilesystemVault(rootPath, FilesystemVaultPollInterval(10*time.Millisecond))
+		w, err := v.Watch(".")
+		require.NoError(t, err)
+		defer w.Close()
+
+		require.NoError(t, os.MkdirAll(path.Join(rootPath, "sub"), 0755))
+		require.NoError(t, ioutil.WriteFile(path.Join(rootPath, "sub", "file.txt"), []byte("a"), 0644))
+		assert.Equal(t, WatchEvent{Type: WatchEventCreate, Path: "sub/file.txt"}, receiveWatchEvent(t, w))
+	})
+}
+
+func TestDiffFileStates(t *testing.T) {
+	prev := map[string]fsFileState{
+		"same.txt":    {modTime: time.Unix(1, 0), size: 1},
+		"modtime.txt": {modTime: time.Unix(1, 0), size: 1},
+		"size.txt":    {modTime: time.Unix(1, 0), size: 1},
+		"removed.txt": {modTime: time.Unix(1, 0), size: 1},
+	}
+	cur := map[string]fsFileState{
+		"same.txt":    {modTime: time.Unix(1, 0), size: 1},
+		"modtime.txt": {modTime: time.Unix(2, 0), size: 1},
+		"size.txt":    {modTime: time.Unix(1, 0), size: 2},
+		"created.txt": {modTime: time.Unix(1, 0), size: 1},
+	}
+
+	assert.Equal(t,
+		[]WatchEvent{
+			{Type: WatchEventCreate, Path: "created.txt"},
+			{Type: WatchEventModify, Path: "modtime.txt"},
+			{Type: WatchEventRemove, Path: "removed.txt"},
+			{Type: WatchEventModify, Path: "size.txt"},
+		},
+		diffFileStates(prev, cur),
+	)
+}
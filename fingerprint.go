@@ -0,0 +1,100 @@
+package goblin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	// FingerprintManifestPath is the path in a vault where the manifest of
+	// fingerprinted file paths is stored.
+	FingerprintManifestPath = ".goblin/fingerprints.json"
+
+	fingerprintLength = 8
+)
+
+// fingerprintPath returns the path of the file with a fingerprint of its contents
+// inserted before the file extension, such as app.3f9a1c2b.js for app.js.
+func fingerprintPath(name string, data []byte) string {
+	hash := sha256.Sum256(data)
+	fingerprint := hex.EncodeToString(hash[:])[:fingerprintLength]
+
+	ext := path.Ext(name)
+	if ext == path.Base(name) {
+		// Files like .htaccess are all extension, so treat them as having none
+		ext = ""
+	}
+
+	return strings.TrimSuffix(name, ext) + "." + fingerprint + ext
+}
+
+// Fingerprints maps original file paths to their fingerprinted paths in a vault
+// built with the MemoryBuilderFingerprint option.
+type Fingerprints struct {
+	paths map[string]string
+}
+
+// LoadFingerprints loads the fingerprint manifest from the provided vault. If the
+// vault doesn't have a fingerprint manifest, such as a FilesystemVault used during
+// development, an empty set of fingerprints is returned and paths are returned
+// unchanged.
+func LoadFingerprints(v Vault) (*Fingerprints, error) {
+	fp := &Fingerprints{
+		paths: map[string]string{},
+	}
+
+	data, err := v.ReadFile(FingerprintManifestPath)
+	if os.IsNotExist(err) {
+		return fp, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &fp.paths)
+	if err != nil {
+		return nil, err
+	}
+
+	return fp, nil
+}
+
+// Lookup returns the fingerprinted path for the original path, if there is one.
+func (fp *Fingerprints) Lookup(name string) (string, bool) {
+	fpPath, ok := fp.paths[strings.TrimPrefix(name, pathSeparator)]
+	if !ok {
+		return "", false
+	}
+
+	if strings.HasPrefix(name, pathSeparator) {
+		fpPath = pathSeparator + fpPath
+	}
+
+	return fpPath, true
+}
+
+// Path returns the fingerprinted path for the original path, or the original path
+// if it wasn't fingerprinted. A leading slash on the path is preserved so URL paths
+// can be used directly.
+func (fp *Fingerprints) Path(name string) string {
+	if fpPath, ok := fp.Lookup(name); ok {
+		return fpPath
+	}
+
+	return name
+}
+
+// FuncMap returns template functions for using fingerprinted paths in templates.
+// The result can be passed to the Funcs method of an html/template or text/template
+// Template. It provides an "asset" function that returns the fingerprinted path of
+// the path it's given, for example:
+//
+//	<script src="{{ asset "/static/app.js" }}"></script>
+func (fp *Fingerprints) FuncMap() map[string]interface{} {
+	return map[string]interface{}{
+		"asset": fp.Path,
+	}
+}
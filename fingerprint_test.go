@@ -0,0 +1,94 @@
+package goblin
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprintPath(t *testing.T) {
+	data := []byte("console.log('app');")
+
+	t.Run("with extension", func(t *testing.T) {
+		assert.Regexp(t, `^static/app\.[0-9a-f]{8}\.js$`, fingerprintPath("static/app.js", data))
+	})
+
+	t.Run("with multiple extensions", func(t *testing.T) {
+		assert.Regexp(t, `^app\.min\.[0-9a-f]{8}\.js$`, fingerprintPath("app.min.js", data))
+	})
+
+	t.Run("without extension", func(t *testing.T) {
+		assert.Regexp(t, `^LICENSE\.[0-9a-f]{8}$`, fingerprintPath("LICENSE", data))
+	})
+
+	t.Run("dot file", func(t *testing.T) {
+		assert.Regexp(t, `^dir/\.htaccess\.[0-9a-f]{8}$`, fingerprintPath("dir/.htaccess", data))
+	})
+
+	t.Run("changes with content", func(t *testing.T) {
+		assert.NotEqual(t,
+			fingerprintPath("app.js", data),
+			fingerprintPath("app.js", []byte("changed")),
+		)
+		assert.Equal(t,
+			fingerprintPath("app.js", data),
+			fingerprintPath("app.js", data),
+		)
+	})
+}
+
+func TestLoadFingerprints(t *testing.T) {
+	t.Run("no manifest", func(t *testing.T) {
+		fp, err := LoadFingerprints(NewMemoryVault())
+		require.NoError(t, err)
+
+		assert.Equal(t, "/static/app.js", fp.Path("/static/app.js"))
+		_, ok := fp.Lookup("static/app.js")
+		assert.False(t, ok)
+	})
+
+	t.Run("with manifest", func(t *testing.T) {
+		v := NewMemoryVault()
+		require.NoError(t, v.WriteFile(
+			FingerprintManifestPath,
+			bytes.NewBufferString(`{"static/app.js": "static/app.0123abcd.js"}`),
+		))
+
+		fp, err := LoadFingerprints(v)
+		require.NoError(t, err)
+
+		assert.Equal(t, "static/app.0123abcd.js", fp.Path("static/app.js"))
+		assert.Equal(t, "/static/app.0123abcd.js", fp.Path("/static/app.js"))
+		assert.Equal(t, "static/other.js", fp.Path("static/other.js"))
+
+		fpPath, ok := fp.Lookup("static/app.js")
+		assert.True(t, ok)
+		assert.Equal(t, "static/app.0123abcd.js", fpPath)
+	})
+
+	t.Run("invalid manifest", func(t *testing.T) {
+		v := NewMemoryVault()
+		require.NoError(t, v.WriteFile(FingerprintManifestPath, bytes.NewBufferString(`[`)))
+
+		_, err := LoadFingerprints(v)
+		assert.Error(t, err)
+	})
+}
+
+func TestFingerprintsFuncMap(t *testing.T) {
+	fp := &Fingerprints{paths: map[string]string{
+		"static/app.js": "static/app.0123abcd.js",
+	}}
+
+	tmpl, err := template.New("page").Funcs(fp.FuncMap()).Parse(
+		`<script src="{{ asset "/static/app.js" }}"></script>`,
+	)
+	require.NoError(t, err)
+
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, tmpl.Execute(buf, nil))
+	assert.Equal(t, `<script src="/static/app.0123abcd.js"></script>`, buf.String())
+}
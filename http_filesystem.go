@@ -0,0 +1,135 @@
+package goblin
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// HTTPFileSystem returns an http.FileSystem that serves files from the provided vault,
+// allowing a vault to be used with http.FileServer or any other code expecting an
+// http.FileSystem. Files are read into memory when they're opened so they can be
+// seeked regardless of the vault type.
+func HTTPFileSystem(v Vault) http.FileSystem {
+	return &httpFileSystem{v: v}
+}
+
+type httpFileSystem struct {
+	v Vault
+}
+
+var _ http.FileSystem = &httpFileSystem{}
+
+func (hfs *httpFileSystem) Open(name string) (http.File, error) {
+	name = strings.TrimPrefix(name, pathSeparator)
+	if name == "" {
+		name = filesystemRootPath
+	}
+
+	fInfo, err := hfs.v.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if fInfo.IsDir() {
+		return &httpDir{
+			v:     hfs.v,
+			name:  name,
+			fInfo: fInfo,
+		}, nil
+	}
+
+	data, err := hfs.v.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &httpFile{
+		Reader: bytes.NewReader(data),
+		fInfo:  fInfo,
+	}, nil
+}
+
+type httpFile struct {
+	*bytes.Reader
+	fInfo os.FileInfo
+}
+
+var _ http.File = &httpFile{}
+
+func (hf *httpFile) Close() error {
+	return nil
+}
+
+func (hf *httpFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, os.ErrInvalid
+}
+
+func (hf *httpFile) Stat() (os.FileInfo, error) {
+	return hf.fInfo, nil
+}
+
+type httpDir struct {
+	v     Vault
+	name  string
+	fInfo os.FileInfo
+
+	entries []os.FileInfo
+	read    bool
+}
+
+var _ http.File = &httpDir{}
+
+func (hd *httpDir) Close() error {
+	return nil
+}
+
+func (hd *httpDir) Read(buf []byte) (int, error) {
+	return 0, os.ErrInvalid
+}
+
+func (hd *httpDir) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == io.SeekStart {
+		hd.entries = nil
+		hd.read = false
+		return 0, nil
+	}
+
+	return 0, os.ErrInvalid
+}
+
+// Readdir follows the semantics of os.File.Readdir.
+func (hd *httpDir) Readdir(count int) ([]os.FileInfo, error) {
+	if !hd.read {
+		entries, err := hd.v.ReadDir(hd.name)
+		if err != nil {
+			return nil, err
+		}
+		hd.entries = entries
+		hd.read = true
+	}
+
+	if count <= 0 {
+		res := hd.entries
+		hd.entries = nil
+		return res, nil
+	}
+
+	if len(hd.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	if count > len(hd.entries) {
+		count = len(hd.entries)
+	}
+	res := hd.entries[:count]
+	hd.entries = hd.entries[count:]
+
+	return res, nil
+}
+
+func (hd *httpDir) Stat() (os.FileInfo, error) {
+	return hd.fInfo, nil
+}
@@ -0,0 +1,70 @@
+package goblin
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPFileSystem(t *testing.T) {
+	t.Run("open file", func(t *testing.T) {
+		hfs := HTTPFileSystem(newTestFileServerVault())
+
+		f, err := hfs.Open("/static/app.js")
+		require.NoError(t, err)
+		defer f.Close()
+
+		_, err = f.Seek(8, io.SeekStart)
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(f)
+		require.NoError(t, err)
+		assert.Equal(t, "log('app');", string(data))
+
+		fInfo, err := f.Stat()
+		require.NoError(t, err)
+		assert.Equal(t, "app.js", fInfo.Name())
+	})
+
+	t.Run("open missing file", func(t *testing.T) {
+		hfs := HTTPFileSystem(newTestFileServerVault())
+
+		_, err := hfs.Open("/static/missing.js")
+		assert.Error(t, err)
+	})
+
+	t.Run("read directory", func(t *testing.T) {
+		hfs := HTTPFileSystem(newTestFileServerVault())
+
+		f, err := hfs.Open("/")
+		require.NoError(t, err)
+		defer f.Close()
+
+		infos, err := f.Readdir(2)
+		require.NoError(t, err)
+		require.Len(t, infos, 2)
+		assert.Equal(t, "docs", infos[0].Name())
+		assert.Equal(t, "empty", infos[1].Name())
+
+		infos, err = f.Readdir(-1)
+		require.NoError(t, err)
+		require.Len(t, infos, 2)
+		assert.Equal(t, "index.html", infos[0].Name())
+		assert.Equal(t, "static", infos[1].Name())
+
+		_, err = f.Readdir(1)
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("use with http.FileServer", func(t *testing.T) {
+		h := http.FileServer(HTTPFileSystem(newTestFileServerVault()))
+		rec := serveTestRequest(h, httptest.NewRequest(http.MethodGet, "/docs/", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "<html>docs</html>", rec.Body.String())
+	})
+}
@@ -0,0 +1,62 @@
+// Package atomicfile writes files so readers only ever see the old or the new
+// contents, never a partially written file.
+package atomicfile
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile calls write with a temporary file in the same directory as the file at
+// the provided path, then replaces the file with it. If write returns an error, the
+// temporary file is removed and the existing file is left untouched.
+func WriteFile(filePath string, perm os.FileMode, write func(w io.Writer) error) (err error) {
+	f, err := ioutil.TempFile(filepath.Dir(filePath), tempPattern(filePath))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	err = write(f)
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	if err != nil {
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	// ioutil.TempFile always creates files with 0600 permissions.
+	err = os.Chmod(f.Name(), perm)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), filePath)
+}
+
+// IsTempFile reports whether the path is one of the temporary files WriteFile uses to
+// write the file at filePath.
+func IsTempFile(filePath string, tempPath string) bool {
+	if filepath.Dir(filePath) != filepath.Dir(tempPath) {
+		return false
+	}
+
+	match, err := filepath.Match(tempPattern(filePath), filepath.Base(tempPath))
+	return err == nil && match
+}
+
+func tempPattern(filePath string) string {
+	return "." + filepath.Base(filePath) + ".tmp*"
+}
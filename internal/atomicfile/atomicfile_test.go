@@ -0,0 +1,73 @@
+package atomicfile
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFile(t *testing.T) {
+	td, err := ioutil.TempDir("", "goblintest")
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	filePath := filepath.Join(td, "out.go")
+
+	t.Run("write new file", func(t *testing.T) {
+		err := WriteFile(filePath, 0644, func(w io.Writer) error {
+			_, err := io.WriteString(w, "first")
+			return err
+		})
+		require.NoError(t, err)
+
+		data, err := ioutil.ReadFile(filePath)
+		require.NoError(t, err)
+		assert.Equal(t, "first", string(data))
+
+		fInfo, err := os.Stat(filePath)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0644), fInfo.Mode().Perm())
+	})
+
+	t.Run("failed write keeps existing file", func(t *testing.T) {
+		err := WriteFile(filePath, 0644, func(w io.Writer) error {
+			_, err := io.WriteString(w, "partial")
+			require.NoError(t, err)
+			return fmt.Errorf("write failed")
+		})
+		assert.EqualError(t, err, "write failed")
+
+		data, err := ioutil.ReadFile(filePath)
+		require.NoError(t, err)
+		assert.Equal(t, "first", string(data))
+
+		files, err := ioutil.ReadDir(td)
+		require.NoError(t, err)
+		assert.Len(t, files, 1)
+	})
+}
+
+func TestIsTempFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		tempPath string
+		expected bool
+	}{
+		{name: "temp file", tempPath: "web/.out.go.tmp123456", expected: true},
+		{name: "file itself", tempPath: "web/out.go", expected: false},
+		{name: "other directory", tempPath: "other/.out.go.tmp123456", expected: false},
+		{name: "other file", tempPath: "web/.app.go.tmp123456", expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, IsTempFile("web/out.go", test.tempPath))
+		})
+	}
+}
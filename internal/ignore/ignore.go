@@ -0,0 +1,220 @@
+// Package ignore matches paths against .gitignore style patterns.
+package ignore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+type rule struct {
+	pattern string
+	negate  bool
+	dirOnly bool
+	re      *regexp.Regexp
+}
+
+// Matcher matches slash-separated paths relative to a root directory against
+// .gitignore style patterns.
+//
+// Patterns support "!" to negate a pattern, a trailing "/" to only match
+// directories, a leading or middle "/" to anchor the pattern to the root and "**"
+// to match any number of directories. As with git, the last matching pattern
+// wins and a path can't be re-included if one of its parent directories is
+// ignored.
+type Matcher struct {
+	rules []rule
+}
+
+// New creates a new matcher with the provided patterns.
+func New(patterns ...string) (*Matcher, error) {
+	m := &Matcher{}
+
+	err := m.AddPatterns(patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// AddPatterns adds patterns to the matcher. Blank patterns and comments starting
+// with "#" are skipped.
+func (m *Matcher) AddPatterns(patterns ...string) error {
+	for _, pattern := range patterns {
+		r, ok, err := parseRule(pattern)
+		if err != nil {
+			return err
+		}
+		if ok {
+			m.rules = append(m.rules, r)
+		}
+	}
+
+	return nil
+}
+
+// AddReader adds the patterns in r, one per line, to the matcher.
+func (m *Matcher) AddReader(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		err := m.AddPatterns(scanner.Text())
+		if err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// AddFile adds the patterns in the file at the provided path to the matcher.
+func (m *Matcher) AddFile(filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = m.AddReader(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	return nil
+}
+
+// Len returns the number of patterns in the matcher.
+func (m *Matcher) Len() int {
+	return len(m.rules)
+}
+
+// Match reports whether the path, or one of its parent directories, is ignored.
+func (m *Matcher) Match(name string, isDir bool) bool {
+	name = strings.Trim(name, "/")
+	if name == "" || name == "." || len(m.rules) == 0 {
+		return false
+	}
+
+	for idx := strings.Index(name, "/"); idx >= 0; {
+		if m.matchPath(name[:idx], true) {
+			return true
+		}
+
+		next := strings.Index(name[idx+1:], "/")
+		if next < 0 {
+			break
+		}
+		idx += next + 1
+	}
+
+	return m.matchPath(name, isDir)
+}
+
+func (m *Matcher) matchPath(name string, isDir bool) bool {
+	ignored := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.re.MatchString(name) {
+			ignored = !r.negate
+		}
+	}
+
+	return ignored
+}
+
+func parseRule(line string) (rule, bool, error) {
+	pattern := trimTrailingSpace(line)
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return rule{}, false, nil
+	}
+
+	r := rule{pattern: pattern}
+
+	if strings.HasPrefix(pattern, "!") {
+		r.negate = true
+		pattern = pattern[1:]
+	} else if strings.HasPrefix(pattern, `\!`) || strings.HasPrefix(pattern, `\#`) {
+		pattern = pattern[1:]
+	}
+
+	if strings.HasSuffix(pattern, "/") {
+		r.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	if pattern == "" {
+		return rule{}, false, nil
+	}
+
+	// A pattern with a separator at the beginning or in the middle is relative to
+	// the root, otherwise it matches at any level.
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	expr := globToRegexp(pattern)
+	if !anchored && !strings.HasPrefix(expr, "(?:.*/)?") {
+		expr = "(?:.*/)?" + expr
+	}
+
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return rule{}, false, fmt.Errorf("invalid pattern %s: %w", line, err)
+	}
+	r.re = re
+
+	return r, true, nil
+}
+
+// trimTrailingSpace removes trailing spaces from the line unless they're escaped
+// with a backslash.
+func trimTrailingSpace(line string) string {
+	line = strings.TrimRight(line, "\r\n")
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+
+	return line
+}
+
+func globToRegexp(pattern string) string {
+	var sb strings.Builder
+
+	for idx := 0; idx < len(pattern); idx++ {
+		c := pattern[idx]
+		switch {
+		case strings.HasPrefix(pattern[idx:], "**/") && (idx == 0 || pattern[idx-1] == '/'):
+			sb.WriteString("(?:.*/)?")
+			idx += 2
+		case pattern[idx:] == "**" && idx > 0 && pattern[idx-1] == '/':
+			sb.WriteString(".*")
+			idx++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[idx+1:], ']')
+			if end < 0 {
+				sb.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+			class := pattern[idx+1 : idx+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			idx += end + 1
+		case c == '\\' && idx+1 < len(pattern):
+			idx++
+			sb.WriteString(regexp.QuoteMeta(string(pattern[idx])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return sb.String()
+}
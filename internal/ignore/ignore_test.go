@@ -0,0 +1,72 @@
+package ignore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		match    bool
+	}{
+		{"name at root", []string{"*.map"}, "app.js.map", false, true},
+		{"name in subdir", []string{"*.map"}, "static/js/app.js.map", false, true},
+		{"name not matching", []string{"*.map"}, "static/js/app.js", false, false},
+		{"directory contents", []string{"node_modules"}, "web/node_modules/left-pad/index.js", false, true},
+		{"dir only matches dir", []string{"build/"}, "build", true, true},
+		{"dir only skips file", []string{"build/"}, "build", false, false},
+		{"dir only contents", []string{"build/"}, "build/app.js", false, true},
+		{"anchored at root", []string{"/build"}, "build/app.js", false, true},
+		{"anchored not in subdir", []string{"/build"}, "web/build/app.js", false, false},
+		{"middle separator anchors", []string{"web/*.tmp"}, "web/a.tmp", false, true},
+		{"middle separator no subdir", []string{"web/*.tmp"}, "src/web/a.tmp", false, false},
+		{"star does not cross dirs", []string{"web/*.tmp"}, "web/sub/a.tmp", false, false},
+		{"leading double star", []string{"**/logs"}, "a/b/logs/today.log", false, true},
+		{"trailing double star", []string{"logs/**"}, "logs/a/b.log", false, true},
+		{"trailing double star not dir", []string{"logs/**"}, "logs", true, false},
+		{"middle double star", []string{"a/**/b"}, "a/x/y/b", false, true},
+		{"middle double star no dirs", []string{"a/**/b"}, "a/b", false, true},
+		{"question mark", []string{"file?.txt"}, "file1.txt", false, true},
+		{"char class", []string{"file[0-9].txt"}, "file5.txt", false, true},
+		{"negated char class", []string{"file[!0-9].txt"}, "file5.txt", false, false},
+		{"negation", []string{"*.log", "!keep.log"}, "keep.log", false, false},
+		{"negation then ignore", []string{"*.log", "!keep.log", "keep.log"}, "keep.log", false, true},
+		{"no re-include in ignored dir", []string{"logs/", "!logs/keep.log"}, "logs/keep.log", false, true},
+		{"re-include with dir contents", []string{"logs/*", "!logs/keep.log"}, "logs/keep.log", false, false},
+		{"escaped hash", []string{`\#notes`}, "#notes", false, true},
+		{"escaped bang", []string{`\!important`}, "!important", false, true},
+		{"editor temp files", []string{"*~", ".*.swp"}, "src/.index.html.swp", false, true},
+		{"comment", []string{"# *.js"}, "app.js", false, false},
+		{"trailing spaces", []string{"*.js   "}, "app.js", false, true},
+		{"root never matches", []string{"*"}, ".", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(tt.patterns...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.match, m.Match(tt.path, tt.isDir))
+		})
+	}
+}
+
+func TestMatcherAddReader(t *testing.T) {
+	m, err := New()
+	require.NoError(t, err)
+
+	err = m.AddReader(strings.NewReader("# build output\n\ndist/\n*.map\r\n!vendor.js.map\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, m.Len())
+
+	assert.True(t, m.Match("dist/app.js", false))
+	assert.True(t, m.Match("app.js.map", false))
+	assert.False(t, m.Match("vendor.js.map", false))
+	assert.False(t, m.Match("app.js", false))
+}
@@ -0,0 +1,208 @@
+// Package inputcache records the state of the files and directories a vault is
+// built from so a rebuild can be skipped when none of them have changed.
+package inputcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aphistic/goblin/internal/atomicfile"
+)
+
+// Entry is the state of a single input.
+type Entry struct {
+	Dir     bool  `json:"dir,omitempty"`
+	Size    int64 `json:"size"`
+	ModTime int64 `json:"mtime"`
+	// Hash is the SHA-256 of a file's contents or a directory's sorted entry names.
+	// It's empty if the input doesn't exist or its contents aren't known yet.
+	Hash string `json:"hash,omitempty"`
+}
+
+// State is the state of a set of inputs, keyed by path.
+type State map[string]Entry
+
+// Paths returns the sorted paths of the inputs in the state.
+func (s State) Paths() []string {
+	paths := make([]string, 0, len(s))
+	for inputPath := range s {
+		paths = append(paths, inputPath)
+	}
+	sort.Strings(paths)
+
+	return paths
+}
+
+// IgnoreFunc reports whether a path should be left out of a scan, such as a file
+// written by the build itself.
+type IgnoreFunc func(inputPath string) bool
+
+// Scan returns the state of the inputs at the provided paths. File contents are
+// only hashed if their modified time or size differs from the previous state, so
+// scanning unchanged inputs is cheap. Inputs that don't exist have an empty Entry.
+// If ignore isn't nil, ignored inputs and directory entries are skipped.
+func Scan(paths []string, prev State, ignore IgnoreFunc) (State, error) {
+	if ignore == nil {
+		ignore = func(string) bool { return false }
+	}
+
+	cur := State{}
+	for _, inputPath := range paths {
+		if ignore(inputPath) {
+			continue
+		}
+
+		fInfo, err := os.Stat(inputPath)
+		if os.IsNotExist(err) {
+			cur[inputPath] = Entry{}
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		entry := Entry{
+			Dir:     fInfo.IsDir(),
+			Size:    fInfo.Size(),
+			ModTime: fInfo.ModTime().UnixNano(),
+		}
+
+		if entry.Dir {
+			// A directory's modified time changes whenever a file in it is written
+			// with a rename, so its entries are compared instead.
+			entry.Size = 0
+			entry.ModTime = 0
+			entry.Hash, err = hashDir(inputPath, ignore)
+		} else if prevEntry, ok := prev[inputPath]; ok && prevEntry.Hash != "" &&
+			prevEntry.Size == entry.Size && prevEntry.ModTime == entry.ModTime {
+			entry.Hash = prevEntry.Hash
+		} else {
+			entry.Hash, err = hashFile(inputPath)
+		}
+		if os.IsNotExist(err) {
+			cur[inputPath] = Entry{}
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		cur[inputPath] = entry
+	}
+
+	return cur, nil
+}
+
+// Forget clears the hash of any file modified at or after the provided time so it's
+// reported as changed by the next scan. This is used for files that may have changed
+// again while a build was reading them.
+func (s State) Forget(since time.Time) {
+	for inputPath, entry := range s {
+		if !entry.Dir && entry.ModTime >= since.UnixNano() {
+			entry.Hash = ""
+			s[inputPath] = entry
+		}
+	}
+}
+
+// Changed returns the sorted paths of the inputs that differ between the two states.
+func Changed(prev State, cur State) []string {
+	var changed []string
+	for inputPath, curEntry := range cur {
+		prevEntry, ok := prev[inputPath]
+		if !ok || prevEntry.Dir != curEntry.Dir || prevEntry.Hash != curEntry.Hash {
+			changed = append(changed, inputPath)
+		}
+	}
+	for inputPath := range prev {
+		if _, ok := cur[inputPath]; !ok {
+			changed = append(changed, inputPath)
+		}
+	}
+	sort.Strings(changed)
+
+	return changed
+}
+
+func hashFile(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashDir(dirPath string, ignore IgnoreFunc) (string, error) {
+	fInfos, err := ioutil.ReadDir(dirPath)
+	if err != nil {
+		return "", err
+	}
+
+	// ReadDir sorts entries by name, so the hash doesn't depend on the order the
+	// filesystem returns them in.
+	names := make([]string, 0, len(fInfos))
+	for _, fInfo := range fInfos {
+		if ignore(filepath.Join(dirPath, fInfo.Name())) {
+			continue
+		}
+		names = append(names, fInfo.Name())
+	}
+	h := sha256.Sum256([]byte(strings.Join(names, "\x00")))
+
+	return hex.EncodeToString(h[:]), nil
+}
+
+// Cache is the state of a vault's inputs from its last build, stored in a file
+// between runs.
+type Cache struct {
+	// Key identifies the options the vault was built with. A cache is only valid
+	// for a build with the same key.
+	Key    string `json:"key"`
+	Inputs State  `json:"inputs"`
+}
+
+// Load reads the cache file at the provided path. If the file doesn't exist, an
+// empty cache is returned.
+func Load(cachePath string) (*Cache, error) {
+	data, err := ioutil.ReadFile(cachePath)
+	if os.IsNotExist(err) {
+		return &Cache{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	c := &Cache{}
+	err = json.Unmarshal(data, c)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Save writes the cache to the file at the provided path.
+func (c *Cache) Save(cachePath string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(cachePath, 0644, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
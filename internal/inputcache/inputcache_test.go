@@ -0,0 +1,119 @@
+package inputcache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScan(t *testing.T) {
+	td, err := ioutil.TempDir("", "goblintest")
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	dirPath := filepath.Join(td, "static")
+	filePath := filepath.Join(dirPath, "app.js")
+	missingPath := filepath.Join(td, ".goblinignore")
+	require.NoError(t, os.MkdirAll(dirPath, 0755))
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("app"), 0644))
+
+	paths := []string{dirPath, filePath, missingPath}
+	state, err := Scan(paths, nil, nil)
+	require.NoError(t, err)
+	assert.True(t, state[dirPath].Dir)
+	assert.NotEmpty(t, state[filePath].Hash)
+	assert.Equal(t, Entry{}, state[missingPath])
+	assert.Equal(t, []string{missingPath, dirPath, filePath}, state.Paths())
+
+	t.Run("unchanged", func(t *testing.T) {
+		cur, err := Scan(paths, state, nil)
+		require.NoError(t, err)
+		assert.Empty(t, Changed(state, cur))
+	})
+
+	t.Run("touched without changes", func(t *testing.T) {
+		later := time.Now().Add(time.Hour)
+		require.NoError(t, os.Chtimes(filePath, later, later))
+
+		cur, err := Scan(paths, state, nil)
+		require.NoError(t, err)
+		assert.Empty(t, Changed(state, cur))
+	})
+
+	t.Run("modified and created", func(t *testing.T) {
+		require.NoError(t, ioutil.WriteFile(filePath, []byte("new app"), 0644))
+		require.NoError(t, ioutil.WriteFile(missingPath, []byte("*.map"), 0644))
+		defer os.Remove(missingPath)
+
+		cur, err := Scan(paths, state, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{missingPath, filePath}, Changed(state, cur))
+	})
+
+	t.Run("file added to directory", func(t *testing.T) {
+		cur, err := Scan(paths, nil, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "app.css"), []byte("css"), 0644))
+		next, err := Scan(paths, cur, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{dirPath}, Changed(cur, next))
+	})
+
+	t.Run("ignored paths", func(t *testing.T) {
+		outPath := filepath.Join(dirPath, "goblin_assets.go")
+		ignore := func(inputPath string) bool {
+			return inputPath == outPath
+		}
+
+		cur, err := Scan(append(paths, outPath), nil, ignore)
+		require.NoError(t, err)
+		assert.NotContains(t, cur, outPath)
+
+		require.NoError(t, ioutil.WriteFile(outPath, []byte("package assets"), 0644))
+		defer os.Remove(outPath)
+
+		next, err := Scan(append(paths, outPath), cur, ignore)
+		require.NoError(t, err)
+		assert.Empty(t, Changed(cur, next))
+	})
+
+	t.Run("forget recently modified files", func(t *testing.T) {
+		cur, err := Scan(paths, nil, nil)
+		require.NoError(t, err)
+
+		cur.Forget(time.Now().Add(-time.Minute))
+		assert.Empty(t, cur[filePath].Hash)
+
+		next, err := Scan(paths, cur, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{filePath}, Changed(cur, next))
+	})
+}
+
+func TestCache(t *testing.T) {
+	td, err := ioutil.TempDir("", "goblintest")
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	cachePath := filepath.Join(td, ".goblin_assets.cache")
+
+	c, err := Load(cachePath)
+	require.NoError(t, err)
+	assert.Equal(t, &Cache{}, c)
+
+	c = &Cache{
+		Key:    "key",
+		Inputs: State{"index.html": {Size: 5, ModTime: 10, Hash: "abc"}},
+	}
+	require.NoError(t, c.Save(cachePath))
+
+	loaded, err := Load(cachePath)
+	require.NoError(t, err)
+	assert.Equal(t, c, loaded)
+}
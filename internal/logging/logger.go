@@ -1,37 +1,207 @@
 // Package logging is the logger the Goblin binary uses internally.
 package logging
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
 
-// Logger is an interface representint a logger.
-type Logger interface {
-	Printf(string, ...interface{})
+// Level is the severity of a log message.
+type Level int
+
+const (
+	// LevelDebug is for detailed messages only shown in verbose mode.
+	LevelDebug Level = iota - 1
+	// LevelInfo is for progress messages.
+	LevelInfo
+	// LevelWarn is for problems that don't stop the command.
+	LevelWarn
+	// LevelError is for problems that stop the command.
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Format is the format log messages are written in.
+type Format int
+
+const (
+	// FormatText writes messages as human readable lines of text.
+	FormatText Format = iota
+	// FormatJSON writes each message as a JSON object on its own line, using the same
+	// keys as log/slog's JSON handler.
+	FormatJSON
+)
+
+// badKey is the key used for a value without a key, matching log/slog.
+const badKey = "!BADKEY"
+
+// Logger writes leveled log messages with alternating keys and values to a writer.
+// Messages below the logger's level are discarded.
+type Logger struct {
+	w      io.Writer
+	level  Level
+	format Format
+	now    func() time.Time
+
+	lock sync.Mutex
+}
+
+// New creates a new logger writing messages at or above the level to w.
+func New(w io.Writer, level Level, format Format) *Logger {
+	return &Logger{
+		w:      w,
+		level:  level,
+		format: format,
+		now:    time.Now,
+	}
+}
+
+// Debug logs a message at LevelDebug.
+func (l *Logger) Debug(msg string, args ...interface{}) {
+	l.log(LevelDebug, msg, args)
+}
+
+// Info logs a message at LevelInfo.
+func (l *Logger) Info(msg string, args ...interface{}) {
+	l.log(LevelInfo, msg, args)
+}
+
+// Warn logs a message at LevelWarn.
+func (l *Logger) Warn(msg string, args ...interface{}) {
+	l.log(LevelWarn, msg, args)
 }
 
-// NilLogger is a Logger that does nothing.
-type NilLogger struct{}
+// Error logs a message at LevelError.
+func (l *Logger) Error(msg string, args ...interface{}) {
+	l.log(LevelError, msg, args)
+}
+
+type attr struct {
+	key   string
+	value interface{}
+}
+
+func (l *Logger) log(level Level, msg string, args []interface{}) {
+	if level < l.level {
+		return
+	}
+
+	attrs := parseAttrs(args)
+
+	var line string
+	if l.format == FormatJSON {
+		line = l.jsonLine(level, msg, attrs)
+	} else {
+		line = textLine(level, msg, attrs)
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	// Errors writing log messages have nowhere to be reported, so they're ignored.
+	_, _ = io.WriteString(l.w, line)
+}
+
+// parseAttrs pairs up alternating keys and values the same way log/slog does.
+func parseAttrs(args []interface{}) []attr {
+	var attrs []attr
+	for idx := 0; idx < len(args); idx++ {
+		key, ok := args[idx].(string)
+		if !ok || idx+1 == len(args) {
+			attrs = append(attrs, attr{key: badKey, value: args[idx]})
+			continue
+		}
 
-var _ Logger = &NilLogger{}
+		attrs = append(attrs, attr{key: key, value: args[idx+1]})
+		idx++
+	}
+
+	return attrs
+}
 
-// NewNilLogger creates a new nil logger.
-func NewNilLogger() *NilLogger {
-	return &NilLogger{}
+// attrValue converts errors and other types with a string form to strings so they
+// are written the same way in both formats.
+func attrValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return v
+	}
 }
 
-// Printf does nothing.
-func (l *NilLogger) Printf(format string, args ...interface{}) {}
+// textLine formats a message as a line of text. Info messages are written without a
+// level so normal output is easy to read.
+func textLine(level Level, msg string, attrs []attr) string {
+	var sb strings.Builder
+	if level != LevelInfo {
+		sb.WriteString(level.String())
+		sb.WriteString(": ")
+	}
+	sb.WriteString(msg)
 
-// PrintfLogger uses fmt.Printf for logging.
-type PrintfLogger struct{}
+	for _, a := range attrs {
+		value := fmt.Sprint(attrValue(a.value))
+		if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+			value = strconv.Quote(value)
+		}
 
-var _ Logger = &PrintfLogger{}
+		sb.WriteString(" ")
+		sb.WriteString(a.key)
+		sb.WriteString("=")
+		sb.WriteString(value)
+	}
+	sb.WriteString("\n")
 
-// NewPrintfLogger creates a new fmt.Printf logger.
-func NewPrintfLogger() *PrintfLogger {
-	return &PrintfLogger{}
+	return sb.String()
 }
 
-// Printf uses fmt.Printf to log messages.
-func (l *PrintfLogger) Printf(format string, args ...interface{}) {
-	fmt.Printf(format, args...)
+func (l *Logger) jsonLine(level Level, msg string, attrs []attr) string {
+	var sb strings.Builder
+	sb.WriteString("{")
+	writeJSONField(&sb, "time", l.now().Format(time.RFC3339Nano))
+	sb.WriteString(",")
+	writeJSONField(&sb, "level", level.String())
+	sb.WriteString(",")
+	writeJSONField(&sb, "msg", msg)
+	for _, a := range attrs {
+		sb.WriteString(",")
+		writeJSONField(&sb, a.key, attrValue(a.value))
+	}
+	sb.WriteString("}\n")
+
+	return sb.String()
+}
+
+func writeJSONField(sb *strings.Builder, key string, value interface{}) {
+	keyData, _ := json.Marshal(key)
+	valueData, err := json.Marshal(value)
+	if err != nil {
+		valueData, _ = json.Marshal(fmt.Sprint(value))
+	}
+
+	sb.Write(keyData)
+	sb.WriteString(":")
+	sb.Write(valueData)
 }
@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger(t *testing.T) {
+	t.Run("text", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		l := New(buf, LevelInfo, FormatText)
+
+		l.Debug("Excluded file", "path", "app.js.map")
+		l.Info("Added file", "path", "index.html", "size", "5 B")
+		l.Warn("Skipped symlinked directory", "path", "my dir")
+		l.Error("Build failed", "error", fmt.Errorf("bad = input"), "elapsed", 2*time.Second)
+		l.Info("Odd args", "path")
+
+		assert.Equal(t, "Added file path=index.html size=\"5 B\"\n"+
+			"WARN: Skipped symlinked directory path=\"my dir\"\n"+
+			"ERROR: Build failed error=\"bad = input\" elapsed=2s\n"+
+			"Odd args !BADKEY=path\n", buf.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		l := New(buf, LevelDebug, FormatJSON)
+		l.now = func() time.Time {
+			return time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+		}
+
+		l.Debug("Excluded file", "path", "app.js.map")
+		l.Error("Build failed", "error", fmt.Errorf("bad input"), "files", 3)
+
+		assert.Equal(t, `{"time":"2020-06-01T12:00:00Z","level":"DEBUG","msg":"Excluded file","path":"app.js.map"}`+"\n"+
+			`{"time":"2020-06-01T12:00:00Z","level":"ERROR","msg":"Build failed","error":"bad input","files":3}`+"\n",
+			buf.String())
+	})
+
+	t.Run("quiet", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		l := New(buf, LevelWarn, FormatText)
+
+		l.Info("Added file", "path", "index.html")
+		l.Warn("Skipped symlinked directory", "path", "dir")
+		assert.Equal(t, "WARN: Skipped symlinked directory path=dir\n", buf.String())
+	})
+}
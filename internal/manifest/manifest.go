@@ -0,0 +1,516 @@
+// Package manifest reads the manifest files describing vaults for the goblin
+// utility to build.
+package manifest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest describes one or more vaults to build.
+type Manifest struct {
+	// Vaults are the vaults to build, in the order they appear in the manifest.
+	Vaults []*Vault
+}
+
+// Vault describes how to build a single vault. Paths are relative to the
+// manifest's directory.
+type Vault struct {
+	Name         string   `yaml:"name"`
+	Package      string   `yaml:"package"`
+	Output       string   `yaml:"output"`
+	Binary       bool     `yaml:"binary"`
+	Embed        bool     `yaml:"embed"`
+	ExportLoader bool     `yaml:"export-loader"`
+	Accessors    bool     `yaml:"accessors"`
+	LoaderFormat string   `yaml:"loader-format"`
+	Compression  string   `yaml:"compression"`
+	Precompress  []string `yaml:"precompress"`
+	Fingerprint  []string `yaml:"fingerprint"`
+	Exclude      []string `yaml:"exclude"`
+	IgnoreFiles  []string `yaml:"ignore-files"`
+	MaxDepth     int      `yaml:"max-depth"`
+	ModTime      string   `yaml:"mtime"`
+	Roots        []*Root  `yaml:"roots"`
+
+	Transforms  []*Transform `yaml:"transforms"`
+	MaxSize     string       `yaml:"max-size"`
+	MaxFileSize string       `yaml:"max-file-size"`
+	Budgets     []*Budget    `yaml:"budgets"`
+
+	// CompressionLevel is the gzip compression level parsed from Compression.
+	CompressionLevel int `yaml:"-"`
+	// ClampModTime is the time parsed from ModTime, or the zero time if it isn't set.
+	ClampModTime time.Time `yaml:"-"`
+	// MaxSizeBytes is the size parsed from MaxSize, or 0 if there's no limit.
+	MaxSizeBytes int64 `yaml:"-"`
+	// MaxFileSizeBytes is the size parsed from MaxFileSize, or 0 if there's no limit.
+	MaxFileSizeBytes int64 `yaml:"-"`
+}
+
+// Root describes files to include in a vault from a single directory.
+type Root struct {
+	Path          string    `yaml:"path"`
+	Prefix        string    `yaml:"prefix"`
+	Include       []string  `yaml:"include"`
+	IncludeDirs   []string  `yaml:"include-dirs"`
+	StripSegments int       `yaml:"strip-segments"`
+	Rename        []*Rename `yaml:"rename"`
+}
+
+// Transform is a transform applied to files matching a glob, either a built-in
+// transform or an external command.
+type Transform struct {
+	Glob      string   `yaml:"glob"`
+	Transform string   `yaml:"transform"`
+	Exec      []string `yaml:"exec"`
+}
+
+// Budget limits the total size of the files matching a glob.
+type Budget struct {
+	Glob    string `yaml:"glob"`
+	MaxSize string `yaml:"max-size"`
+
+	// MaxSizeBytes is the size parsed from MaxSize.
+	MaxSizeBytes int64 `yaml:"-"`
+}
+
+// Rename is a rule to rename included paths matching a regular expression.
+type Rename struct {
+	Pattern     string `yaml:"pattern"`
+	Replacement string `yaml:"replacement"`
+
+	// Regexp is the compiled Pattern.
+	Regexp *regexp.Regexp `yaml:"-"`
+}
+
+var (
+	vaultNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	compressionLevels = map[string]int{
+		"none":    gzip.NoCompression,
+		"fastest": gzip.BestSpeed,
+		"default": gzip.DefaultCompression,
+		"best":    gzip.BestCompression,
+	}
+)
+
+// ParseCompression parses a compression level name (none, fastest, default or
+// best) or a gzip level from 0 to 9.
+func ParseCompression(compression string) (int, error) {
+	if compression == "" {
+		return gzip.DefaultCompression, nil
+	}
+
+	if level, ok := compressionLevels[compression]; ok {
+		return level, nil
+	}
+
+	level, err := strconv.Atoi(compression)
+	if err != nil || level < gzip.NoCompression || level > gzip.BestCompression {
+		return 0, fmt.Errorf(
+			"invalid compression %s, expected none, fastest, default, best or 0-9",
+			compression,
+		)
+	}
+
+	return level, nil
+}
+
+// ParseModTime parses a time to clamp modified times to, either as a Unix timestamp
+// or in RFC 3339 format. An empty string is parsed as the zero time.
+func ParseModTime(modTime string) (time.Time, error) {
+	if modTime == "" {
+		return time.Time{}, nil
+	}
+
+	if seconds, err := strconv.ParseInt(modTime, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, modTime)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid mtime %s, expected a Unix timestamp or RFC 3339 time", modTime)
+	}
+
+	return t, nil
+}
+
+// ParseSize parses a size in bytes with an optional unit, such as 500KB or 10MiB.
+func ParseSize(size string) (int64, error) {
+	bytes, err := humanize.ParseBytes(size)
+	if err != nil || bytes > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %s, expected a number of bytes such as 500KB or 10MiB", size)
+	}
+
+	return int64(bytes), nil
+}
+
+// Error is a problem with a manifest entry.
+type Error struct {
+	File string
+	Line int
+	// Vault is the name of the vault the error is for, if known.
+	Vault string
+	Msg   string
+}
+
+func (e *Error) Error() string {
+	if e.Vault != "" {
+		return fmt.Sprintf("%s:%d: vault %s: %s", e.File, e.Line, e.Vault, e.Msg)
+	}
+
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// Load reads and validates the manifest at the provided path.
+func Load(manifestPath string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(manifestPath, data)
+}
+
+// Parse parses and validates the manifest data, which can be YAML or JSON. The
+// manifest path is used in errors and to resolve relative paths in the manifest.
+func Parse(manifestPath string, data []byte) (*Manifest, error) {
+	p := &parser{
+		file:    manifestPath,
+		baseDir: filepath.Dir(manifestPath),
+	}
+
+	return p.parse(data)
+}
+
+type parser struct {
+	file    string
+	baseDir string
+}
+
+func (p *parser) errorf(node *yaml.Node, vault string, format string, args ...interface{}) error {
+	return &Error{
+		File:  p.file,
+		Line:  node.Line,
+		Vault: vault,
+		Msg:   fmt.Sprintf(format, args...),
+	}
+}
+
+func (p *parser) parse(data []byte) (*Manifest, error) {
+	var doc yaml.Node
+	err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&doc)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %w", p.file, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("%s: manifest is empty", p.file)
+	}
+
+	root := doc.Content[0]
+	err = p.checkFields(root, "", "vaults")
+	if err != nil {
+		return nil, err
+	}
+
+	vaultsNode := fieldValue(root, "vaults")
+	if vaultsNode == nil || len(vaultsNode.Content) == 0 {
+		return nil, p.errorf(root, "", "no vaults defined")
+	}
+	if vaultsNode.Kind != yaml.SequenceNode {
+		return nil, p.errorf(vaultsNode, "", "vaults must be a list")
+	}
+
+	m := &Manifest{}
+	names := map[string]int{}
+	outputs := map[string]int{}
+	for idx, vaultNode := range vaultsNode.Content {
+		v, err := p.parseVault(vaultNode, idx)
+		if err != nil {
+			return nil, err
+		}
+
+		if line, ok := names[v.Name]; ok {
+			return nil, p.errorf(vaultNode, v.Name, "name is already used on line %d", line)
+		}
+		names[v.Name] = vaultNode.Line
+
+		if line, ok := outputs[v.Output]; ok {
+			return nil, p.errorf(vaultNode, v.Name, "output %s is already used on line %d", v.Output, line)
+		}
+		outputs[v.Output] = vaultNode.Line
+
+		m.Vaults = append(m.Vaults, v)
+	}
+
+	return m, nil
+}
+
+func (p *parser) parseVault(node *yaml.Node, idx int) (*Vault, error) {
+	// Until the name is known, errors refer to the vault by its position.
+	vaultDesc := fmt.Sprintf("#%d", idx+1)
+
+	if node.Kind != yaml.MappingNode {
+		return nil, p.errorf(node, vaultDesc, "expected a mapping")
+	}
+
+	v := &Vault{}
+	err := node.Decode(v)
+	if err != nil {
+		return nil, fmt.Errorf("%s: vault %s: %w", p.file, vaultDesc, err)
+	}
+
+	if v.Name == "" {
+		return nil, p.errorf(node, vaultDesc, "name is required")
+	}
+	if !vaultNameRegexp.MatchString(v.Name) {
+		return nil, p.errorf(fieldValue(node, "name"), vaultDesc,
+			"name %q must only contain letters, numbers and underscores", v.Name)
+	}
+	vaultDesc = v.Name
+
+	err = p.checkFields(node, vaultDesc,
+		"name", "package", "output", "binary", "embed", "export-loader", "accessors",
+		"loader-format", "compression", "precompress", "fingerprint", "exclude",
+		"ignore-files", "max-depth", "mtime", "roots", "transforms", "max-size",
+		"max-file-size", "budgets",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.Package == "" {
+		v.Package = v.Name
+	}
+	if v.Output == "" {
+		v.Output = fmt.Sprintf("goblin_%s.go", v.Name)
+	}
+	v.Output = p.resolvePath(v.Output)
+
+	if v.Binary && v.Embed {
+		return nil, p.errorf(fieldValue(node, "embed"), vaultDesc, "binary and embed cannot be used together")
+	}
+
+	switch v.LoaderFormat {
+	case "", "bytes", "string":
+	default:
+		return nil, p.errorf(fieldValue(node, "loader-format"), vaultDesc,
+			"invalid loader-format %s, expected bytes or string", v.LoaderFormat)
+	}
+
+	v.CompressionLevel, err = ParseCompression(v.Compression)
+	if err != nil {
+		return nil, p.errorf(fieldValue(node, "compression"), vaultDesc, "%s", err)
+	}
+
+	precompressNode := fieldValue(node, "precompress")
+	for idx, encoding := range v.Precompress {
+		if encoding != "gzip" && encoding != "deflate" {
+			return nil, p.errorf(precompressNode.Content[idx], vaultDesc,
+				"invalid precompress encoding %s, expected gzip or deflate", encoding)
+		}
+	}
+
+	v.ClampModTime, err = ParseModTime(v.ModTime)
+	if err != nil {
+		return nil, p.errorf(fieldValue(node, "mtime"), vaultDesc, "%s", err)
+	}
+
+	if v.MaxDepth < 0 {
+		return nil, p.errorf(fieldValue(node, "max-depth"), vaultDesc, "max-depth cannot be negative")
+	}
+
+	if v.MaxSize != "" {
+		v.MaxSizeBytes, err = ParseSize(v.MaxSize)
+		if err != nil {
+			return nil, p.errorf(fieldValue(node, "max-size"), vaultDesc, "%s", err)
+		}
+	}
+	if v.MaxFileSize != "" {
+		v.MaxFileSizeBytes, err = ParseSize(v.MaxFileSize)
+		if err != nil {
+			return nil, p.errorf(fieldValue(node, "max-file-size"), vaultDesc, "%s", err)
+		}
+	}
+
+	budgetsNode := fieldValue(node, "budgets")
+	for idx, budget := range v.Budgets {
+		err = p.checkBudget(budgetsNode.Content[idx], vaultDesc, budget)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	transformsNode := fieldValue(node, "transforms")
+	for idx, transform := range v.Transforms {
+		err = p.checkTransform(transformsNode.Content[idx], vaultDesc, transform)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rootsNode := fieldValue(node, "roots")
+	if rootsNode == nil || len(v.Roots) == 0 {
+		return nil, p.errorf(node, vaultDesc, "at least one root is required")
+	}
+	for idx, root := range v.Roots {
+		err = p.checkRoot(rootsNode.Content[idx], vaultDesc, root)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return v, nil
+}
+
+func (p *parser) checkRoot(node *yaml.Node, vaultDesc string, root *Root) error {
+	err := p.checkFields(node, vaultDesc,
+		"path", "prefix", "include", "include-dirs", "strip-segments", "rename",
+	)
+	if err != nil {
+		return err
+	}
+
+	if root.Path == "" {
+		return p.errorf(node, vaultDesc, "root path is required")
+	}
+	root.Path = p.resolvePath(root.Path)
+
+	if root.StripSegments < 0 {
+		return p.errorf(fieldValue(node, "strip-segments"), vaultDesc, "strip-segments cannot be negative")
+	}
+
+	// A root without any includes includes everything in it.
+	if len(root.Include) == 0 && len(root.IncludeDirs) == 0 {
+		root.IncludeDirs = []string{"."}
+	}
+
+	renameNode := fieldValue(node, "rename")
+	for idx, rename := range root.Rename {
+		if rename == nil || rename.Pattern == "" {
+			return p.errorf(renameNode.Content[idx], vaultDesc, "rename pattern is required")
+		}
+
+		rename.Regexp, err = regexp.Compile(rename.Pattern)
+		if err != nil {
+			return p.errorf(fieldValue(renameNode.Content[idx], "pattern"), vaultDesc,
+				"invalid rename pattern: %s", err)
+		}
+	}
+
+	return nil
+}
+
+func (p *parser) checkBudget(node *yaml.Node, vaultDesc string, budget *Budget) error {
+	err := p.checkFields(node, vaultDesc, "glob", "max-size")
+	if err != nil {
+		return err
+	}
+
+	if budget.Glob == "" {
+		return p.errorf(node, vaultDesc, "budget glob is required")
+	}
+	if _, err := path.Match(budget.Glob, ""); err != nil {
+		return p.errorf(fieldValue(node, "glob"), vaultDesc, "invalid budget glob %s: %s", budget.Glob, err)
+	}
+
+	if budget.MaxSize == "" {
+		return p.errorf(node, vaultDesc, "budget max-size is required")
+	}
+	budget.MaxSizeBytes, err = ParseSize(budget.MaxSize)
+	if err != nil {
+		return p.errorf(fieldValue(node, "max-size"), vaultDesc, "%s", err)
+	}
+
+	return nil
+}
+
+func (p *parser) checkTransform(node *yaml.Node, vaultDesc string, transform *Transform) error {
+	err := p.checkFields(node, vaultDesc, "glob", "transform", "exec")
+	if err != nil {
+		return err
+	}
+
+	if transform.Glob == "" {
+		return p.errorf(node, vaultDesc, "transform glob is required")
+	}
+	if _, err := path.Match(transform.Glob, ""); err != nil {
+		return p.errorf(fieldValue(node, "glob"), vaultDesc, "invalid transform glob %s: %s", transform.Glob, err)
+	}
+
+	switch {
+	case transform.Transform == "" && len(transform.Exec) == 0:
+		return p.errorf(node, vaultDesc, "transform or exec is required")
+	case transform.Transform != "" && len(transform.Exec) > 0:
+		return p.errorf(fieldValue(node, "exec"), vaultDesc, "transform and exec cannot be used together")
+	}
+
+	switch transform.Transform {
+	case "", "compact-json", "lf", "trim-trailing-space":
+	default:
+		return p.errorf(fieldValue(node, "transform"), vaultDesc,
+			"invalid transform %s, expected compact-json, lf or trim-trailing-space", transform.Transform)
+	}
+
+	return nil
+}
+
+// checkFields returns an error for any keys in the mapping node that aren't in the
+// list of known fields, which are usually typos.
+func (p *parser) checkFields(node *yaml.Node, vaultDesc string, fields ...string) error {
+	if node.Kind != yaml.MappingNode {
+		return p.errorf(node, vaultDesc, "expected a mapping")
+	}
+
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		key := node.Content[idx]
+
+		known := false
+		for _, field := range fields {
+			if key.Value == field {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return p.errorf(key, vaultDesc, "unknown field %s, expected one of: %s",
+				key.Value, strings.Join(fields, ", "))
+		}
+	}
+
+	return nil
+}
+
+func (p *parser) resolvePath(filePath string) string {
+	if filepath.IsAbs(filePath) {
+		return filePath
+	}
+
+	return filepath.Join(p.baseDir, filepath.FromSlash(filePath))
+}
+
+// fieldValue returns the value node for the key in the mapping node, or nil if the
+// key isn't in the mapping.
+func fieldValue(node *yaml.Node, key string) *yaml.Node {
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		if node.Content[idx].Value == key {
+			return node.Content[idx+1]
+		}
+	}
+
+	return nil
+}
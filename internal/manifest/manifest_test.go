@@ -0,0 +1,308 @@
+package manifest
+
+import (
+	"compress/gzip"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("yaml", func(t *testing.T) {
+		m, err := Parse(filepath.Join("build", "goblin.yaml"), []byte(`
+vaults:
+  - name: assets
+    package: web
+    output: web/goblin_assets.go
+    embed: true
+    export-loader: true
+    accessors: true
+    loader-format: string
+    compression: best
+    precompress: [gzip, deflate]
+    fingerprint: ["*.js"]
+    exclude: [node_modules, "*.map"]
+    ignore-files: [.gitignore]
+    max-depth: 3
+    mtime: 2020-06-01T12:00:00Z
+    transforms:
+      - glob: "*.json"
+        transform: compact-json
+      - glob: "*.css"
+        exec: [esbuild, --minify, --loader=css]
+    max-size: 50MB
+    max-file-size: 1MiB
+    budgets:
+      - glob: "*.png"
+        max-size: 500KB
+    roots:
+      - path: frontend/dist
+        prefix: static
+        include: ["*.html"]
+        strip-segments: 1
+        rename:
+          - pattern: '\.min\.js$'
+            replacement: .js
+  - name: migrations
+    roots:
+      - path: /srv/db/migrations
+`))
+		require.NoError(t, err)
+		require.Len(t, m.Vaults, 2)
+
+		assets := m.Vaults[0]
+		assert.Equal(t, "assets", assets.Name)
+		assert.Equal(t, "web", assets.Package)
+		assert.Equal(t, filepath.Join("build", "web", "goblin_assets.go"), assets.Output)
+		assert.True(t, assets.Embed)
+		assert.True(t, assets.ExportLoader)
+		assert.True(t, assets.Accessors)
+		assert.Equal(t, "string", assets.LoaderFormat)
+		assert.Equal(t, gzip.BestCompression, assets.CompressionLevel)
+		assert.Equal(t, []string{"gzip", "deflate"}, assets.Precompress)
+		assert.Equal(t, []string{"*.js"}, assets.Fingerprint)
+		assert.Equal(t, []string{"node_modules", "*.map"}, assets.Exclude)
+		assert.Equal(t, []string{".gitignore"}, assets.IgnoreFiles)
+		assert.Equal(t, 3, assets.MaxDepth)
+		assert.Equal(t, time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC), assets.ClampModTime)
+		assert.Equal(t, []*Transform{
+			{Glob: "*.json", Transform: "compact-json"},
+			{Glob: "*.css", Exec: []string{"esbuild", "--minify", "--loader=css"}},
+		}, assets.Transforms)
+		assert.Equal(t, int64(50000000), assets.MaxSizeBytes)
+		assert.Equal(t, int64(1<<20), assets.MaxFileSizeBytes)
+		require.Len(t, assets.Budgets, 1)
+		assert.Equal(t, "*.png", assets.Budgets[0].Glob)
+		assert.Equal(t, int64(500000), assets.Budgets[0].MaxSizeBytes)
+
+		require.Len(t, assets.Roots, 1)
+		root := assets.Roots[0]
+		assert.Equal(t, filepath.Join("build", "frontend", "dist"), root.Path)
+		assert.Equal(t, "static", root.Prefix)
+		assert.Equal(t, []string{"*.html"}, root.Include)
+		assert.Empty(t, root.IncludeDirs)
+		assert.Equal(t, 1, root.StripSegments)
+		require.Len(t, root.Rename, 1)
+		assert.Equal(t, "static/app.js", root.Rename[0].Regexp.ReplaceAllString("static/app.min.js", root.Rename[0].Replacement))
+
+		migrations := m.Vaults[1]
+		assert.Equal(t, "migrations", migrations.Package)
+		assert.Equal(t, filepath.Join("build", "goblin_migrations.go"), migrations.Output)
+		assert.Equal(t, gzip.DefaultCompression, migrations.CompressionLevel)
+		assert.True(t, migrations.ClampModTime.IsZero())
+		require.Len(t, migrations.Roots, 1)
+		assert.Equal(t, "/srv/db/migrations", migrations.Roots[0].Path)
+		assert.Equal(t, []string{"."}, migrations.Roots[0].IncludeDirs)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		m, err := Parse("goblin.json", []byte(`{
+  "vaults": [
+    {"name": "assets", "binary": true, "output": "assets.bin", "roots": [{"path": "web"}]}
+  ]
+}`))
+		require.NoError(t, err)
+		require.Len(t, m.Vaults, 1)
+		assert.True(t, m.Vaults[0].Binary)
+		assert.Equal(t, "assets.bin", m.Vaults[0].Output)
+		assert.Equal(t, "web", m.Vaults[0].Roots[0].Path)
+	})
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		err      string
+	}{
+		{"empty", ``, "goblin.yaml: manifest is empty"},
+		{"invalid yaml", "vaults: [", "goblin.yaml: yaml: line 1: did not find expected node content"},
+		{"unknown top level field", "valts: []", "goblin.yaml:1: unknown field valts, expected one of: vaults"},
+		{"no vaults", "vaults: []", "goblin.yaml:1: no vaults defined"},
+		{"vaults not a list", "vaults:\n  name: assets", "goblin.yaml:2: vaults must be a list"},
+		{"vault not a mapping", "vaults:\n  - assets", "goblin.yaml:2: vault #1: expected a mapping"},
+		{
+			"missing name",
+			"vaults:\n  - name: assets\n    roots: [{path: web}]\n  - package: web\n",
+			"goblin.yaml:4: vault #2: name is required",
+		},
+		{
+			"invalid name",
+			"vaults:\n  - name: my-assets\n",
+			`goblin.yaml:2: vault #1: name "my-assets" must only contain letters, numbers and underscores`,
+		},
+		{
+			"wrong type",
+			"vaults:\n  - name: assets\n    max-depth: deep\n",
+			"goblin.yaml: vault #1: yaml: unmarshal errors:\n  line 3: cannot unmarshal !!str `deep` into int",
+		},
+		{
+			"unknown field",
+			"vaults:\n  - name: assets\n    exclude: [a]\n    exlcude: [b]\n",
+			"goblin.yaml:4: vault assets: unknown field exlcude, expected one of: name, package, output, " +
+				"binary, embed, export-loader, accessors, loader-format, compression, precompress, " +
+				"fingerprint, exclude, ignore-files, max-depth, mtime, roots, transforms, max-size, " +
+				"max-file-size, budgets",
+		},
+		{
+			"binary and embed",
+			"vaults:\n  - name: assets\n    binary: true\n    embed: true\n",
+			"goblin.yaml:4: vault assets: binary and embed cannot be used together",
+		},
+		{
+			"invalid loader format",
+			"vaults:\n  - name: assets\n    loader-format: hex\n",
+			"goblin.yaml:3: vault assets: invalid loader-format hex, expected bytes or string",
+		},
+		{
+			"invalid compression",
+			"vaults:\n  - name: assets\n    compression: 10\n",
+			"goblin.yaml:3: vault assets: invalid compression 10, expected none, fastest, default, best or 0-9",
+		},
+		{
+			"invalid precompress",
+			"vaults:\n  - name: assets\n    precompress:\n      - gzip\n      - br\n",
+			"goblin.yaml:5: vault assets: invalid precompress encoding br, expected gzip or deflate",
+		},
+		{
+			"invalid mtime",
+			"vaults:\n  - name: assets\n    mtime: yesterday\n",
+			"goblin.yaml:3: vault assets: invalid mtime yesterday, expected a Unix timestamp or RFC 3339 time",
+		},
+		{
+			"negative max depth",
+			"vaults:\n  - name: assets\n    max-depth: -1\n",
+			"goblin.yaml:3: vault assets: max-depth cannot be negative",
+		},
+		{
+			"invalid max size",
+			"vaults:\n  - name: assets\n    max-size: huge\n",
+			"goblin.yaml:3: vault assets: invalid size huge, expected a number of bytes such as 500KB or 10MiB",
+		},
+		{
+			"budget without max size",
+			"vaults:\n  - name: assets\n    budgets:\n      - glob: '*.png'\n",
+			"goblin.yaml:4: vault assets: budget max-size is required",
+		},
+		{
+			"budget without glob",
+			"vaults:\n  - name: assets\n    budgets:\n      - max-size: 1MB\n",
+			"goblin.yaml:4: vault assets: budget glob is required",
+		},
+		{
+			"transform without glob",
+			"vaults:\n  - name: assets\n    transforms:\n      - transform: lf\n",
+			"goblin.yaml:4: vault assets: transform glob is required",
+		},
+		{
+			"invalid transform glob",
+			"vaults:\n  - name: assets\n    transforms:\n      - glob: '[x'\n        transform: lf\n",
+			"goblin.yaml:4: vault assets: invalid transform glob [x: syntax error in pattern",
+		},
+		{
+			"transform without transform or exec",
+			"vaults:\n  - name: assets\n    transforms:\n      - glob: '*.sql'\n",
+			"goblin.yaml:4: vault assets: transform or exec is required",
+		},
+		{
+			"transform and exec",
+			"vaults:\n  - name: assets\n    transforms:\n      - glob: '*.css'\n        transform: lf\n        exec: [csso]\n",
+			"goblin.yaml:6: vault assets: transform and exec cannot be used together",
+		},
+		{
+			"unknown transform",
+			"vaults:\n  - name: assets\n    transforms:\n      - glob: '*.js'\n        transform: minify\n",
+			"goblin.yaml:5: vault assets: invalid transform minify, expected compact-json, lf or trim-trailing-space",
+		},
+		{
+			"no roots",
+			"vaults:\n  - name: assets\n",
+			"goblin.yaml:2: vault assets: at least one root is required",
+		},
+		{
+			"root without path",
+			"vaults:\n  - name: assets\n    roots:\n      - prefix: static\n",
+			"goblin.yaml:4: vault assets: root path is required",
+		},
+		{
+			"unknown root field",
+			"vaults:\n  - name: assets\n    roots:\n      - path: web\n        prefx: static\n",
+			"goblin.yaml:5: vault assets: unknown field prefx, expected one of: path, prefix, include, " +
+				"include-dirs, strip-segments, rename",
+		},
+		{
+			"invalid rename",
+			"vaults:\n  - name: assets\n    roots:\n      - path: web\n        rename:\n          - pattern: '(x'\n",
+			"goblin.yaml:6: vault assets: invalid rename pattern: error parsing regexp: missing closing ): `(x`",
+		},
+		{
+			"duplicate name",
+			"vaults:\n  - name: assets\n    roots: [{path: web}]\n  - name: assets\n    output: other.go\n    roots: [{path: web}]\n",
+			"goblin.yaml:4: vault assets: name is already used on line 2",
+		},
+		{
+			"duplicate output",
+			"vaults:\n  - name: assets\n    output: out.go\n    roots: [{path: web}]\n" +
+				"  - name: other\n    output: out.go\n    roots: [{path: web}]\n",
+			"goblin.yaml:5: vault other: output out.go is already used on line 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse("goblin.yaml", []byte(tt.manifest))
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestParseModTime(t *testing.T) {
+	modTime, err := ParseModTime("")
+	require.NoError(t, err)
+	assert.True(t, modTime.IsZero())
+
+	modTime, err = ParseModTime("1591012800")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC), modTime)
+
+	modTime, err = ParseModTime("2020-06-01T12:00:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC), modTime)
+}
+
+func TestParseCompression(t *testing.T) {
+	level, err := ParseCompression("")
+	require.NoError(t, err)
+	assert.Equal(t, gzip.DefaultCompression, level)
+
+	level, err = ParseCompression("fastest")
+	require.NoError(t, err)
+	assert.Equal(t, gzip.BestSpeed, level)
+
+	level, err = ParseCompression("0")
+	require.NoError(t, err)
+	assert.Equal(t, gzip.NoCompression, level)
+
+	_, err = ParseCompression("fast")
+	assert.EqualError(t, err, "invalid compression fast, expected none, fastest, default, best or 0-9")
+}
+
+func TestParseSize(t *testing.T) {
+	size, err := ParseSize("500")
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), size)
+
+	size, err = ParseSize("10MiB")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10<<20), size)
+
+	size, err = ParseSize("1.5 kB")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1500), size)
+
+	_, err = ParseSize("-1")
+	assert.EqualError(t, err, "invalid size -1, expected a number of bytes such as 500KB or 10MiB")
+}
@@ -0,0 +1,29 @@
+package goblin
+
+// Logger receives log messages from a MemoryBuilder. Each message is logged with a
+// level and alternating keys and values with more detail, such as the path of a file.
+//
+// The methods match those of *slog.Logger from the log/slog package, so a
+// *slog.Logger can be used as a Logger directly:
+//
+//	b := goblin.NewMemoryBuilder(goblin.MemoryBuilderLogger(slog.Default()))
+type Logger interface {
+	// Debug logs detailed messages, such as files that were excluded.
+	Debug(msg string, args ...interface{})
+	// Info logs progress messages, such as files that were added.
+	Info(msg string, args ...interface{})
+	// Warn logs problems that don't stop the build.
+	Warn(msg string, args ...interface{})
+	// Error logs problems that stop the build.
+	Error(msg string, args ...interface{})
+}
+
+// nopLogger is a Logger that discards all messages.
+type nopLogger struct{}
+
+var _ Logger = nopLogger{}
+
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+func (nopLogger) Info(msg string, args ...interface{})  {}
+func (nopLogger) Warn(msg string, args ...interface{})  {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
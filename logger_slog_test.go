@@ -0,0 +1,40 @@
+//go:build go1.21
+// +build go1.21
+
+package goblin
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryBuilderLoggerSlog(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	writeTestFiles(t, td, map[string]string{
+		"app.js":  "app",
+		"app.tmp": "tmp",
+	})
+
+	t.Run("slog logger", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+		b := NewMemoryBuilder(
+			MemoryBuilderLogger(logger),
+			MemoryBuilderExclude("*.tmp"),
+		)
+		require.NoError(t, b.Include(td, []string{"*"}))
+
+		assert.Contains(t, buf.String(), `level=DEBUG msg="Excluded file" path=app.tmp`)
+		assert.Contains(t, buf.String(), `level=INFO msg="Added file" path=app.js`)
+	})
+}
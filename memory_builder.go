@@ -2,14 +2,21 @@ package goblin
 
 import (
 	"bytes"
+	"compress/gzip"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/aphistic/goblin/internal/logging"
+	"github.com/aphistic/goblin/internal/ignore"
 	"github.com/dave/jennifer/jen"
 	"github.com/dustin/go-humanize"
 )
@@ -18,6 +25,30 @@ const (
 	goblinImport = "github.com/aphistic/goblin"
 )
 
+// LoaderFormat is the way vault data is stored in code generated by WriteLoader.
+type LoaderFormat int
+
+const (
+	// LoaderFormatBytes stores vault data as a []byte variable with one element
+	// per byte. This is the default.
+	LoaderFormatBytes LoaderFormat = iota
+	// LoaderFormatString stores vault data as an escaped string constant. The
+	// generated file is much smaller and faster to compile, and the data is stored
+	// in the binary's read-only data instead of being writable.
+	LoaderFormatString
+)
+
+func (f LoaderFormat) String() string {
+	switch f {
+	case LoaderFormatBytes:
+		return "bytes"
+	case LoaderFormatString:
+		return "string"
+	default:
+		return "unknown"
+	}
+}
+
 // MemoryBuilderOption is an option used when creating a memory vault builder
 type MemoryBuilderOption func(b *MemoryBuilder)
 
@@ -29,17 +60,153 @@ func MemoryBuilderExportLoader(exportLoader bool) MemoryBuilderOption {
 	}
 }
 
-// MemoryBuilderLogger provides a logger for the builder to use.
-func MemoryBuilderLogger(logger logging.Logger) MemoryBuilderOption {
+// MemoryBuilderLoaderFormat sets the way vault data is stored in code generated by
+// WriteLoader. The default is LoaderFormatBytes.
+func MemoryBuilderLoaderFormat(format LoaderFormat) MemoryBuilderOption {
+	return func(b *MemoryBuilder) {
+		b.loaderFormat = format
+	}
+}
+
+// MemoryBuilderLogger provides a logger for the builder to use. By default, nothing
+// is logged.
+func MemoryBuilderLogger(logger Logger) MemoryBuilderOption {
 	return func(b *MemoryBuilder) {
 		b.logger = logger
 	}
 }
 
+// MemoryBuilderPrecompress will cause compressible files, such as HTML, CSS and
+// JavaScript, to also be stored compressed with each of the provided encodings
+// (EncodingGzip or EncodingDeflate). A compressed variant is only stored if it's
+// smaller than the original file. The variants can be served to HTTP clients by
+// a FileServer based on the Accept-Encoding header.
+func MemoryBuilderPrecompress(encodings ...string) MemoryBuilderOption {
+	return func(b *MemoryBuilder) {
+		b.precompress = append(b.precompress, encodings...)
+	}
+}
+
+// MemoryBuilderFingerprint adds an alias with a fingerprint of the file's contents in
+// its name, such as app.3f9a1c2b.js for app.js, for every included file with a path
+// matching one of the provided globs. Globs without a path separator are matched
+// against the file's name. A manifest mapping original paths to fingerprinted paths
+// is stored in the vault and can be loaded with LoadFingerprints.
+//
+// Fingerprinted paths change whenever the file's contents change, so they can be
+// served with far-future caching headers.
+func MemoryBuilderFingerprint(globs ...string) MemoryBuilderOption {
+	return func(b *MemoryBuilder) {
+		b.fingerprintGlobs = append(b.fingerprintGlobs, globs...)
+	}
+}
+
+// MemoryBuilderAccessors will cause WriteLoader to also generate a constant with the
+// path of each included file and a function returning all of the paths. Constant
+// names are based on the vault name and the file's path, so index.html in the assets
+// vault becomes assetsIndexHTML, or AssetsIndexHTML if the loader is exported. Using
+// the constants instead of string paths means a missing or renamed file causes a
+// compile error instead of an error at runtime.
+func MemoryBuilderAccessors(accessors bool) MemoryBuilderOption {
+	return func(b *MemoryBuilder) {
+		b.accessors = accessors
+	}
+}
+
+// MemoryBuilderExclude prevents files matching any of the provided patterns from
+// being included. Patterns use .gitignore syntax and are matched against the file's
+// path relative to the include root, so "node_modules" excludes every file in a
+// node_modules directory, "*.map" excludes source maps in any directory and
+// "!keep.map" includes a file excluded by an earlier pattern.
+func MemoryBuilderExclude(patterns ...string) MemoryBuilderOption {
+	return func(b *MemoryBuilder) {
+		b.excludes = append(b.excludes, patterns...)
+	}
+}
+
+// MemoryBuilderIgnoreFiles causes the builder to read exclude patterns from files
+// with the provided names, such as ".gitignore" or ".goblinignore", in the include
+// root. Files that don't exist are skipped. Patterns provided with
+// MemoryBuilderExclude are checked after the patterns in ignore files.
+func MemoryBuilderIgnoreFiles(names ...string) MemoryBuilderOption {
+	return func(b *MemoryBuilder) {
+		b.ignoreFiles = append(b.ignoreFiles, names...)
+	}
+}
+
+// MemoryBuilderMaxDepth limits how deep directories are included recursively. A
+// depth of 1 only includes the files directly in a directory matched by an include,
+// 2 also includes files in its subdirectories and so on. The default of 0 has no
+// limit.
+func MemoryBuilderMaxDepth(depth int) MemoryBuilderOption {
+	return func(b *MemoryBuilder) {
+		b.maxDepth = depth
+	}
+}
+
+// MemoryBuilderCompressionLevel sets the gzip compression level used for the vault's
+// binary representation, from gzip.NoCompression to gzip.BestCompression. The
+// default is gzip.DefaultCompression.
+func MemoryBuilderCompressionLevel(level int) MemoryBuilderOption {
+	return func(b *MemoryBuilder) {
+		b.compressionLevel = level
+	}
+}
+
+// MemoryBuilderClampModTime sets the modified time of any included file modified
+// after the provided time to that time, so rebuilding a vault from a fresh checkout
+// produces identical output. See SourceDateEpoch for using the time in the
+// SOURCE_DATE_EPOCH environment variable.
+func MemoryBuilderClampModTime(clampTime time.Time) MemoryBuilderOption {
+	return func(b *MemoryBuilder) {
+		b.clampModTime = clampTime
+	}
+}
+
+// sourceDateEpochEnv is the environment variable reproducible build tools use to
+// provide the time builds should use, as a Unix timestamp.
+const sourceDateEpochEnv = "SOURCE_DATE_EPOCH"
+
+// SourceDateEpoch returns the time in the SOURCE_DATE_EPOCH environment variable
+// used by reproducible build tools. If the variable isn't set, the zero time is
+// returned.
+func SourceDateEpoch() (time.Time, error) {
+	epoch := os.Getenv(sourceDateEpochEnv)
+	if epoch == "" {
+		return time.Time{}, nil
+	}
+
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %s: %w", sourceDateEpochEnv, epoch, err)
+	}
+
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
 // MemoryBuilder creates binary or code representations of a memory vault.
 type MemoryBuilder struct {
-	logger       logging.Logger
-	exportLoader bool
+	logger           Logger
+	exportLoader     bool
+	loaderFormat     LoaderFormat
+	accessors        bool
+	excludes         []string
+	ignoreFiles      []string
+	maxDepth         int
+	compressionLevel int
+	clampModTime     time.Time
+	precompress      []string
+	fingerprintGlobs []string
+	transforms       []globTransformer
+	maxSize          int64
+	maxFileSize      int64
+	budgets          []*sizeBudget
+
+	fingerprints map[string]string
+	included     map[string]struct{}
+	inputs       map[string]struct{}
+	fileSizes    map[string]int64
+	totalSize    int64
 
 	v *MemoryVault
 }
@@ -47,8 +214,13 @@ type MemoryBuilder struct {
 // NewMemoryBuilder creates a new memory builder.
 func NewMemoryBuilder(opts ...MemoryBuilderOption) *MemoryBuilder {
 	b := &MemoryBuilder{
-		logger: logging.NewNilLogger(),
-		v:      NewMemoryVault(),
+		logger:           nopLogger{},
+		compressionLevel: gzip.DefaultCompression,
+		v:                NewMemoryVault(),
+		fingerprints:     map[string]string{},
+		included:         map[string]struct{}{},
+		inputs:           map[string]struct{}{},
+		fileSizes:        map[string]int64{},
 	}
 
 	for _, opt := range opts {
@@ -58,53 +230,460 @@ func NewMemoryBuilder(opts ...MemoryBuilderOption) *MemoryBuilder {
 	return b
 }
 
+// IncludeOption is an option used when including files in a memory vault.
+type IncludeOption func(*includeOptions)
+
+type includeRename struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+type includeOptions struct {
+	prefix        string
+	stripSegments int
+	renames       []includeRename
+}
+
+func newIncludeOptions(opts ...IncludeOption) *includeOptions {
+	incOpts := &includeOptions{}
+	for _, opt := range opts {
+		opt(incOpts)
+	}
+
+	return incOpts
+}
+
+// IncludePrefix adds the prefix to the path of included files in the vault, so
+// including dist/app.js with the prefix "static" adds it as static/dist/app.js.
+func IncludePrefix(prefix string) IncludeOption {
+	return func(incOpts *includeOptions) {
+		incOpts.prefix = strings.Trim(prefix, pathSeparator)
+	}
+}
+
+// IncludeStripSegments removes the provided number of leading directories from the
+// path of included files in the vault, so including dist/js/app.js while stripping
+// one segment adds it as js/app.js. An error is returned if a file doesn't have
+// enough directories to strip.
+func IncludeStripSegments(count int) IncludeOption {
+	return func(incOpts *includeOptions) {
+		incOpts.stripSegments = count
+	}
+}
+
+// IncludeRename replaces matches of the pattern in the path of included files with
+// the replacement, which can refer to submatches as with Regexp.ReplaceAllString.
+// Renames are applied in the order they're provided, after segments are stripped and
+// before the prefix is added.
+func IncludeRename(pattern *regexp.Regexp, replacement string) IncludeOption {
+	return func(incOpts *includeOptions) {
+		incOpts.renames = append(incOpts.renames, includeRename{
+			pattern:     pattern,
+			replacement: replacement,
+		})
+	}
+}
+
+// mapPath converts the path of a file relative to the include root into its path in
+// the vault.
+func (incOpts *includeOptions) mapPath(relPath string) (string, error) {
+	filePath := relPath
+
+	if incOpts.stripSegments > 0 {
+		segments := strings.Split(filePath, pathSeparator)
+		if len(segments) <= incOpts.stripSegments {
+			return "", fmt.Errorf("cannot strip %d segments from %s", incOpts.stripSegments, relPath)
+		}
+		filePath = strings.Join(segments[incOpts.stripSegments:], pathSeparator)
+	}
+
+	for _, rename := range incOpts.renames {
+		filePath = rename.pattern.ReplaceAllString(filePath, rename.replacement)
+	}
+
+	if incOpts.prefix != "" {
+		filePath = incOpts.prefix + pathSeparator + filePath
+	}
+
+	filePath = path.Clean(strings.TrimPrefix(filePath, pathSeparator))
+	if filePath == filesystemRootPath || filePath == ".." || strings.HasPrefix(filePath, "../") {
+		return "", fmt.Errorf("%s was mapped to an invalid path: %s", relPath, filePath)
+	}
+
+	return filePath, nil
+}
+
 // Include iterates over all files in the root path, then includes any file matching
-// one or more of the provided globs in the memory vault being built.
-func (b *MemoryBuilder) Include(rootPath string, globs []string) error {
+// one or more of the provided globs in the memory vault being built. Directories
+// matching a glob are included recursively. Paths in the vault are relative to the
+// root path, then changed by any IncludeOptions.
+func (b *MemoryBuilder) Include(rootPath string, globs []string, opts ...IncludeOption) error {
+	incOpts := newIncludeOptions(opts...)
+
+	excludes, err := b.excludeMatcher(rootPath)
+	if err != nil {
+		return err
+	}
+
 	for _, glob := range globs {
 		if strings.Contains(glob, "..") {
 			return fmt.Errorf(".. cannot be used in include paths")
 		}
 
 		fullPathGlob := filepath.Join(rootPath, glob)
-		globDir := filepath.Dir(fullPathGlob)
-		if !strings.HasSuffix(globDir, string(os.PathSeparator)) {
-			globDir = globDir + string(os.PathSeparator)
-		}
-
+		b.addInput(globDir(fullPathGlob))
 		matches, err := filepath.Glob(fullPathGlob)
 		if err != nil {
-			b.logger.Printf("error with path '%s': %s\n", fullPathGlob, err)
-			return err
+			return fmt.Errorf("invalid include glob %s: %w", fullPathGlob, err)
 		}
 
 		for _, match := range matches {
-			fInfo, err := os.Stat(match)
+			err = b.includePath(rootPath, match, excludes, incOpts)
 			if err != nil {
 				return err
 			}
+		}
+	}
 
-			// TODO Test how this is achieved
-			filePath := strings.TrimPrefix(match, rootPath)
-			filePath = strings.TrimPrefix(filePath, globDir)
-			filePath = strings.TrimPrefix(filePath, pathSeparator)
+	return nil
+}
 
-			b.logger.Printf("Adding: %s... ", filePath)
-			data, err := ioutil.ReadFile(match)
-			if err != nil {
-				return err
+// IncludeDirs recursively includes all files in each of the provided directories,
+// relative to the root path, in the memory vault being built. Unlike Include, the
+// directories are not globs and an error is returned if one doesn't exist or isn't
+// a directory.
+func (b *MemoryBuilder) IncludeDirs(rootPath string, dirs []string, opts ...IncludeOption) error {
+	incOpts := newIncludeOptions(opts...)
+
+	excludes, err := b.excludeMatcher(rootPath)
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range dirs {
+		if strings.Contains(dir, "..") {
+			return fmt.Errorf(".. cannot be used in include paths")
+		}
+
+		fullPath := filepath.Join(rootPath, dir)
+		b.addInput(fullPath)
+		fInfo, err := os.Stat(fullPath)
+		if err != nil {
+			return err
+		}
+		if !fInfo.IsDir() {
+			return fmt.Errorf("not a directory: %s", fullPath)
+		}
+
+		err = b.includePath(rootPath, fullPath, excludes, incOpts)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// includePath includes the file or directory at the full path, which must be in the
+// root path, unless it's excluded.
+func (b *MemoryBuilder) includePath(
+	rootPath string, fullPath string, excludes *ignore.Matcher, incOpts *includeOptions,
+) error {
+	fInfo, err := os.Stat(fullPath)
+	if err != nil {
+		return err
+	}
+
+	if !fInfo.IsDir() {
+		filePath, err := vaultRelPath(rootPath, fullPath)
+		if err != nil {
+			return err
+		}
+		if excludes.Match(filePath, false) {
+			b.logger.Debug("Excluded file", "path", filePath)
+			return nil
+		}
+
+		return b.includeFile(fullPath, filePath, fInfo, incOpts)
+	}
+	b.addInput(fullPath)
+
+	return filepath.Walk(fullPath, func(walkPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		filePath, err := vaultRelPath(rootPath, walkPath)
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if filePath == filesystemRootPath {
+				return nil
+			}
+			if excludes.Match(filePath, true) {
+				b.logger.Debug("Excluded directory", "path", filePath)
+				return filepath.SkipDir
+			}
+			if b.maxDepth > 0 && walkPath != fullPath && pathDepth(fullPath, walkPath) >= b.maxDepth {
+				return filepath.SkipDir
 			}
+			b.addInput(walkPath)
+			return nil
+		}
 
-			err = b.v.WriteFile(
-				filePath,
-				bytes.NewBuffer(data),
-				FileModTime(fInfo.ModTime()),
-			)
+		if info.Mode()&os.ModeSymlink != 0 {
+			// filepath.Walk doesn't follow symlinks, so resolve them to
+			// include the file they point to.
+			info, err = os.Stat(walkPath)
 			if err != nil {
 				return err
 			}
-			b.logger.Printf("%s\n", humanize.Bytes(uint64(len(data))))
+			if info.IsDir() {
+				b.logger.Warn("Skipped symlinked directory", "path", filePath)
+				return nil
+			}
 		}
+
+		if excludes.Match(filePath, false) {
+			b.logger.Debug("Excluded file", "path", filePath)
+			return nil
+		}
+
+		return b.includeFile(walkPath, filePath, info, incOpts)
+	})
+}
+
+func (b *MemoryBuilder) includeFile(
+	fullPath string, relPath string, fInfo os.FileInfo, incOpts *includeOptions,
+) error {
+	filePath, err := incOpts.mapPath(relPath)
+	if err != nil {
+		return err
+	}
+
+	b.addInput(fullPath)
+
+	// Check the size before reading the file so a huge file isn't read into memory.
+	err = b.checkFileSize(filePath, fInfo.Size())
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(fullPath)
+	if err != nil {
+		return err
+	}
+	data, err = b.transform(filePath, data)
+	if err != nil {
+		return err
+	}
+	err = b.addFileSize(filePath, int64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	modTime := fInfo.ModTime()
+	if !b.clampModTime.IsZero() && modTime.After(b.clampModTime) {
+		modTime = b.clampModTime
+	}
+
+	err = b.v.WriteFile(
+		filePath,
+		bytes.NewBuffer(data),
+		FileModTime(modTime),
+	)
+	if err != nil {
+		return err
+	}
+	b.logger.Info("Added file", "path", filePath, "size", humanize.Bytes(uint64(len(data))))
+	b.included[filePath] = struct{}{}
+
+	err = b.writeEncodings(filePath, data)
+	if err != nil {
+		return err
+	}
+
+	return b.writeFingerprint(filePath, data)
+}
+
+func (b *MemoryBuilder) addInput(fullPath string) {
+	b.inputs[filepath.Clean(fullPath)] = struct{}{}
+}
+
+// Inputs returns the sorted paths of the files and directories read while including
+// files in the vault, along with any ignore files. If none of them change, building
+// the vault again with the same options produces the same vault.
+func (b *MemoryBuilder) Inputs() []string {
+	inputs := make([]string, 0, len(b.inputs))
+	for input := range b.inputs {
+		inputs = append(inputs, input)
+	}
+	sort.Strings(inputs)
+
+	return inputs
+}
+
+// globDir returns the directory of the part of a glob before its first wildcard,
+// which is the directory a new match would appear in.
+func globDir(glob string) string {
+	idx := strings.IndexAny(glob, "*?[")
+	if idx < 0 {
+		return filepath.Dir(glob)
+	}
+
+	return filepath.Dir(glob[:idx+1])
+}
+
+// vaultRelPath returns the slash-separated path of the full path relative to the
+// root path.
+func vaultRelPath(rootPath string, fullPath string) (string, error) {
+	if rootPath == "" {
+		rootPath = filesystemRootPath
+	}
+
+	relPath, err := filepath.Rel(rootPath, fullPath)
+	if err != nil {
+		return "", err
+	}
+
+	relPath = filepath.ToSlash(relPath)
+	if relPath == ".." || strings.HasPrefix(relPath, "../") {
+		return "", fmt.Errorf("%s is not in the include root %s", fullPath, rootPath)
+	}
+
+	return relPath, nil
+}
+
+// pathDepth returns the number of directories between the base path and the path.
+func pathDepth(basePath string, fullPath string) int {
+	relPath, err := filepath.Rel(basePath, fullPath)
+	if err != nil || relPath == filesystemRootPath {
+		return 0
+	}
+
+	return strings.Count(filepath.ToSlash(relPath), pathSeparator) + 1
+}
+
+// excludeMatcher creates a matcher with the patterns in any ignore files in the
+// root path followed by the builder's exclude patterns.
+func (b *MemoryBuilder) excludeMatcher(rootPath string) (*ignore.Matcher, error) {
+	m, err := ignore.New()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range b.ignoreFiles {
+		// Missing ignore files are inputs too, since creating one changes the vault.
+		ignorePath := filepath.Join(rootPath, name)
+		b.addInput(ignorePath)
+
+		err = m.AddFile(ignorePath)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	err = m.AddPatterns(b.excludes...)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (b *MemoryBuilder) writeFingerprint(filePath string, data []byte) error {
+	if len(b.fingerprintGlobs) == 0 {
+		return nil
+	}
+
+	matched := false
+	for _, glob := range b.fingerprintGlobs {
+		match, err := matchPathGlob(glob, filePath)
+		if err != nil {
+			return err
+		}
+		if match {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return nil
+	}
+
+	// Remove the alias for a previous version of the file if it was
+	// included more than once.
+	if prevPath, ok := b.fingerprints[filePath]; ok {
+		err := b.v.Remove(prevPath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	fpPath := fingerprintPath(filePath, data)
+	err := b.v.Link(filePath, fpPath)
+	if err != nil {
+		return err
+	}
+	b.fingerprints[filePath] = fpPath
+	b.logger.Debug("Added fingerprinted path", "path", filePath, "fingerprint", fpPath)
+
+	return nil
+}
+
+// writeFingerprintManifest stores the manifest of fingerprinted paths in the vault.
+func (b *MemoryBuilder) writeFingerprintManifest() error {
+	if len(b.fingerprints) == 0 {
+		return nil
+	}
+
+	manifestData, err := json.MarshalIndent(b.fingerprints, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return b.v.WriteFile(
+		FingerprintManifestPath,
+		bytes.NewReader(manifestData),
+		FileModTime(time.Unix(0, 0)),
+	)
+}
+
+// marshalVault returns the binary representation of the memory vault being built.
+func (b *MemoryBuilder) marshalVault() ([]byte, error) {
+	err := b.writeFingerprintManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	return b.v.marshalBinaryLevel(b.compressionLevel)
+}
+
+func (b *MemoryBuilder) writeEncodings(filePath string, data []byte) error {
+	if len(b.precompress) == 0 || !isCompressible(filePath) {
+		return nil
+	}
+
+	for _, encoding := range b.precompress {
+		encData, err := encodeData(encoding, data)
+		if err != nil {
+			return err
+		}
+
+		if len(encData) >= len(data) {
+			continue
+		}
+
+		err = b.v.WriteFileEncoding(filePath, encoding, bytes.NewReader(encData))
+		if err != nil {
+			return err
+		}
+		b.logger.Debug("Added encoded variant", "path", filePath, "encoding", encoding,
+			"size", humanize.Bytes(uint64(len(encData))))
 	}
 
 	return nil
@@ -112,7 +691,7 @@ func (b *MemoryBuilder) Include(rootPath string, globs []string) error {
 
 // WriteBinary writes the binary representation of the memory vault to the provided io.Writer.
 func (b *MemoryBuilder) WriteBinary(w io.Writer) error {
-	vaultData, err := b.v.MarshalBinary()
+	vaultData, err := b.marshalVault()
 	if err != nil {
 		return err
 	}
@@ -126,17 +705,171 @@ func (b *MemoryBuilder) WriteBinary(w io.Writer) error {
 	return nil
 }
 
+// stringLiteralChunkSize is the number of bytes of data in each line of a string
+// literal generated by stringLiteralChunks.
+const stringLiteralChunkSize = 4096
+
+// stringLiteralChunks returns code for a string literal containing the data, split
+// into concatenated chunks so no single line in the generated file is too long.
+// Only printable ASCII is written as-is so the generated file is always valid UTF-8.
+func stringLiteralChunks(data []byte) []jen.Code {
+	if len(data) == 0 {
+		return []jen.Code{jen.Lit("")}
+	}
+
+	const hexDigits = "0123456789abcdef"
+
+	var code []jen.Code
+	for start := 0; start < len(data); start += stringLiteralChunkSize {
+		end := start + stringLiteralChunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		var sb strings.Builder
+		sb.WriteByte('"')
+		for _, c := range data[start:end] {
+			switch {
+			case c == '"' || c == '\\':
+				sb.WriteByte('\\')
+				sb.WriteByte(c)
+			case c >= 0x20 && c < 0x7f:
+				sb.WriteByte(c)
+			default:
+				sb.WriteString(`\x`)
+				sb.WriteByte(hexDigits[c>>4])
+				sb.WriteByte(hexDigits[c&0xf])
+			}
+		}
+		sb.WriteByte('"')
+
+		if start > 0 {
+			code = append(code, jen.Op("+").Line())
+		}
+		code = append(code, jen.Op(sb.String()))
+	}
+
+	return code
+}
+
+// Diff compares the memory vault being built to an existing vault, such as one
+// loaded from a previously generated file, and returns the differences. See
+// DiffVaults for details.
+func (b *MemoryBuilder) Diff(existing Vault) ([]VaultChange, error) {
+	err := b.writeFingerprintManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	return DiffVaults(existing, b.v)
+}
+
+// AppendToFile appends the binary representation of the memory vault to the file at
+// the provided path, usually an executable, so it can be loaded at runtime with
+// LoadAppendedVault. See AppendVaultData for details.
+func (b *MemoryBuilder) AppendToFile(filePath string) error {
+	vaultData, err := b.marshalVault()
+	if err != nil {
+		return err
+	}
+
+	return AppendVaultData(filePath, vaultData)
+}
+
 // WriteLoader writes code and binary data to the provided io.Writer to allow loading the memory
 // vault being built at runtime.
 func (b *MemoryBuilder) WriteLoader(packageName string, vaultName string, w io.Writer) error {
-	vaultData, err := b.v.MarshalBinary()
+	vaultData, err := b.marshalVault()
+	if err != nil {
+		return err
+	}
+
+	genFile := jen.NewFile(packageName)
+	fullVaultName := makeMemoryVaultName(vaultName)
+
+	var loadData jen.Code
+	switch b.loaderFormat {
+	case LoaderFormatBytes:
+		loadData = jen.Id(fullVaultName)
+	case LoaderFormatString:
+		loadData = jen.Index().Byte().Params(jen.Id(fullVaultName))
+	default:
+		return fmt.Errorf("unknown loader format: %d", b.loaderFormat)
+	}
+
+	b.writeLoadFunc(genFile, vaultName, loadData)
+
+	if b.loaderFormat == LoaderFormatString {
+		genFile.Const().Id(fullVaultName).Op("=").Add(stringLiteralChunks(vaultData)...)
+	} else {
+		var vaultValues []jen.Code
+		for _, b := range vaultData {
+			vaultValues = append(vaultValues, jen.LitByte(b))
+		}
+		genFile.Var().Id(fullVaultName).Op("=").Index().Byte().Values(vaultValues...)
+	}
+
+	if b.accessors {
+		b.writeAccessors(genFile, vaultName)
+	}
+
+	err = genFile.Render(w)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// WriteEmbedLoader writes the binary representation of the memory vault being built to
+// binW and code to codeW that uses a //go:embed directive to include the binary file,
+// named binaryName, and load it at runtime. The binary file must be written to the
+// same directory as the code. The generated code requires Go 1.16 or later.
+func (b *MemoryBuilder) WriteEmbedLoader(
+	packageName string, vaultName string, binaryName string, codeW io.Writer, binW io.Writer,
+) error {
+	if binaryName == "" || strings.ContainsAny(binaryName, "/\\") {
+		return fmt.Errorf("embedded binary name must be a file name: %s", binaryName)
+	}
+
+	vaultData, err := b.marshalVault()
 	if err != nil {
 		return err
 	}
 
 	genFile := jen.NewFile(packageName)
+	genFile.Anon("embed")
 	fullVaultName := makeMemoryVaultName(vaultName)
 
+	b.writeLoadFunc(genFile, vaultName, jen.Id(fullVaultName))
+
+	embedPattern := binaryName
+	if strings.ContainsAny(embedPattern, " \t\"") {
+		embedPattern = strconv.Quote(embedPattern)
+	}
+	genFile.Comment(embedDirective + embedPattern).Line().
+		Var().Id(fullVaultName).Index().Byte()
+
+	if b.accessors {
+		b.writeAccessors(genFile, vaultName)
+	}
+
+	err = genFile.Render(codeW)
+	if err != nil {
+		return err
+	}
+
+	_, err = binW.Write(vaultData)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// writeLoadFunc adds the function loading the vault from the provided data to the
+// generated file.
+func (b *MemoryBuilder) writeLoadFunc(genFile *jen.File, vaultName string, loadData jen.Code) {
 	loadPrefix := "loadVault"
 	if b.exportLoader {
 		loadPrefix = "LoadVault"
@@ -147,20 +880,7 @@ func (b *MemoryBuilder) WriteLoader(packageName string, vaultName string, w io.W
 		Params(jen.Qual(goblinImport, "Vault"), jen.Id("error")).
 		Block(
 			jen.Return(
-				jen.Qual(goblinImport, "LoadMemoryVault").Params(jen.Id(fullVaultName)),
+				jen.Qual(goblinImport, "LoadMemoryVault").Params(loadData),
 			),
 		)
-
-	var vaultValues []jen.Code
-	for _, b := range vaultData {
-		vaultValues = append(vaultValues, jen.LitByte(b))
-	}
-	genFile.Var().Id(fullVaultName).Op("=").Index().Byte().Values(vaultValues...)
-
-	err = genFile.Render(w)
-	if err != nil {
-		return err
-	}
-
-	return nil
 }
@@ -0,0 +1,106 @@
+package goblin
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/dave/jennifer/jen"
+)
+
+// accessorInitialisms are words written in all capitals in generated accessor
+// names, following the Go convention of IndexHTML rather than IndexHtml.
+var accessorInitialisms = map[string]struct{}{
+	"API": {}, "CSS": {}, "CSV": {}, "GIF": {}, "HTML": {}, "HTTP": {},
+	"ICO": {}, "ID": {}, "JPG": {}, "JS": {}, "JSON": {}, "PDF": {},
+	"PNG": {}, "SQL": {}, "SVG": {}, "TXT": {}, "URL": {}, "XML": {},
+	"YAML": {}, "YML": {},
+}
+
+// accessorName converts a vault path into the part of a Go identifier used for
+// its generated accessor. For example, "static/index.html" becomes
+// "StaticIndexHTML".
+func accessorName(filePath string) string {
+	words := strings.FieldsFunc(filePath, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var sb strings.Builder
+	for _, word := range words {
+		upper := strings.ToUpper(word)
+		if _, ok := accessorInitialisms[upper]; ok {
+			sb.WriteString(upper)
+			continue
+		}
+
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		sb.WriteString(string(runes))
+	}
+
+	return sb.String()
+}
+
+// writeAccessors adds a constant for the path of every file included in the vault
+// and a function returning all of the paths to the generated file.
+func (b *MemoryBuilder) writeAccessors(genFile *jen.File, vaultName string) {
+	prefix := strings.Title(vaultName)
+	if !b.exportLoader {
+		runes := []rune(prefix)
+		if len(runes) > 0 {
+			runes[0] = unicode.ToLower(runes[0])
+		}
+		prefix = string(runes)
+	}
+	pathsName := prefix + "Paths"
+
+	var paths []string
+	for filePath := range b.included {
+		paths = append(paths, filePath)
+	}
+	sort.Strings(paths)
+
+	usedNames := map[string]struct{}{
+		pathsName: {},
+	}
+
+	var constDefs []jen.Code
+	var pathValues []jen.Code
+	for _, filePath := range paths {
+		baseName := prefix + accessorName(filePath)
+		name := baseName
+		// Different paths can convert to the same name, such as app.js and
+		// app-js, so add a number to the name to keep it unique.
+		for idx := 2; ; idx++ {
+			if _, ok := usedNames[name]; !ok {
+				break
+			}
+			name = baseName + strconv.Itoa(idx)
+		}
+		usedNames[name] = struct{}{}
+
+		constDefs = append(constDefs,
+			jen.Comment(name+" is the path of "+filePath+" in the "+vaultName+" vault."),
+			jen.Id(name).Op("=").Lit(filePath),
+		)
+		pathValues = append(pathValues, jen.Id(name))
+	}
+
+	if len(constDefs) > 0 {
+		genFile.Const().Defs(constDefs...)
+	}
+
+	genFile.Comment(pathsName + " returns the paths of all files included in the " + vaultName + " vault.")
+	genFile.Func().Id(pathsName).
+		Params().
+		Index().String().
+		Block(
+			jen.Return(jen.Index().String().Custom(jen.Options{
+				Open:      "{",
+				Close:     "}",
+				Separator: ",",
+				Multi:     true,
+			}, pathValues...)),
+		)
+}
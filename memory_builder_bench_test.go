@@ -0,0 +1,140 @@
+package goblin
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+var benchLoaderFormats = []LoaderFormat{LoaderFormatBytes, LoaderFormatString}
+
+// newBenchMemoryBuilder creates a builder including a mix of text and random data
+// of roughly the provided size.
+func newBenchMemoryBuilder(b *testing.B, size int, opts ...MemoryBuilderOption) *MemoryBuilder {
+	b.Helper()
+
+	td, err := ioutil.TempDir("", testTempPattern)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	rnd := rand.New(rand.NewSource(1))
+	randData := make([]byte, size/2)
+	rnd.Read(randData)
+
+	textData := bytes.Repeat([]byte("<p>The goblin keeps its treasure in a vault.</p>\n"), size/2/49)
+
+	err = ioutil.WriteFile(filepath.Join(td, "random.bin"), randData, 0644)
+	if err != nil {
+		b.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(td, "index.html"), textData, 0644)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	mb := NewMemoryBuilder(opts...)
+	err = mb.Include(td, []string{"*"})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return mb
+}
+
+// BenchmarkWriteLoader compares the time taken to generate a loader and the size of
+// the generated file for each loader format.
+func BenchmarkWriteLoader(b *testing.B) {
+	for _, format := range benchLoaderFormats {
+		b.Run(format.String(), func(b *testing.B) {
+			mb := newBenchMemoryBuilder(b, 1<<20, MemoryBuilderLoaderFormat(format))
+
+			buf := bytes.NewBuffer(nil)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				buf.Reset()
+				err := mb.WriteLoader("main", "bench", buf)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			b.ReportMetric(float64(buf.Len()), "src-bytes")
+		})
+	}
+}
+
+// BenchmarkBuildLoader compares the time taken to compile a program using a generated
+// loader and the size of the resulting binary for each loader format.
+func BenchmarkBuildLoader(b *testing.B) {
+	goPath, err := exec.LookPath("go")
+	if err != nil {
+		b.Skip("go command not found")
+	}
+
+	for _, format := range benchLoaderFormats {
+		b.Run(format.String(), func(b *testing.B) {
+			mb := newBenchMemoryBuilder(b, 1<<20, MemoryBuilderLoaderFormat(format))
+
+			loaderCode := bytes.NewBuffer(nil)
+			err := mb.WriteLoader("main", "bench", loaderCode)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			// The program is built inside the module so the goblin import resolves
+			// to this checkout. The leading underscore hides it from ./... patterns.
+			pkgDir, err := ioutil.TempDir(".", "_bench")
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer os.RemoveAll(pkgDir)
+
+			err = ioutil.WriteFile(filepath.Join(pkgDir, "goblin_bench.go"), loaderCode.Bytes(), 0644)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			binPath := filepath.Join(pkgDir, "bench.bin")
+			var binSize int64
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				// Change the program each iteration so it isn't served from the
+				// build cache.
+				mainCode := fmt.Sprintf(
+					"package main\n\nconst benchIteration = %d\n\nfunc main() {\n\t_, _ = loadVaultBench()\n}\n",
+					i,
+				)
+				err = ioutil.WriteFile(filepath.Join(pkgDir, "main.go"), []byte(mainCode), 0644)
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.StartTimer()
+
+				out, err := exec.Command(goPath, "build", "-o", binPath, "./"+filepath.Base(pkgDir)).CombinedOutput()
+				if err != nil {
+					b.Fatalf("go build: %s\n%s", err, out)
+				}
+
+				b.StopTimer()
+				binInfo, err := os.Stat(binPath)
+				if err != nil {
+					b.Fatal(err)
+				}
+				binSize = binInfo.Size()
+				b.StartTimer()
+			}
+
+			b.ReportMetric(float64(loaderCode.Len()), "src-bytes")
+			b.ReportMetric(float64(binSize), "bin-bytes")
+		})
+	}
+}
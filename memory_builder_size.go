@@ -0,0 +1,275 @@
+package goblin
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+)
+
+// MemoryBuilderMaxSize causes including a file to fail if it would make the total
+// size of the files in the vault larger than the provided number of bytes. Sizes
+// are measured after any transforms and don't include precompressed variants.
+func MemoryBuilderMaxSize(size int64) MemoryBuilderOption {
+	return func(b *MemoryBuilder) {
+		b.maxSize = size
+	}
+}
+
+// MemoryBuilderMaxFileSize causes including a file larger than the provided number
+// of bytes to fail, such as a video included by accident.
+func MemoryBuilderMaxFileSize(size int64) MemoryBuilderOption {
+	return func(b *MemoryBuilder) {
+		b.maxFileSize = size
+	}
+}
+
+// MemoryBuilderSizeBudget causes including a file to fail if it would make the total
+// size of the files with a vault path matching the glob larger than the provided
+// number of bytes. Globs without a path separator match the file's base name, so
+// "*.png" limits the size of all PNG images in the vault.
+func MemoryBuilderSizeBudget(glob string, size int64) MemoryBuilderOption {
+	return func(b *MemoryBuilder) {
+		b.budgets = append(b.budgets, &sizeBudget{
+			glob: glob,
+			size: size,
+		})
+	}
+}
+
+type sizeBudget struct {
+	glob string
+	size int64
+	used int64
+}
+
+// checkFileSize returns an error if a file's size is over the maximum file size.
+func (b *MemoryBuilder) checkFileSize(filePath string, size int64) error {
+	if b.maxFileSize > 0 && size > b.maxFileSize {
+		return fmt.Errorf("%s is %s, larger than the maximum file size of %s",
+			filePath, humanize.Bytes(uint64(size)), humanize.Bytes(uint64(b.maxFileSize)))
+	}
+
+	return nil
+}
+
+// addFileSize records the size of a file added to the vault, returning an error if
+// it puts the vault over the maximum size or any of its budgets. A file included more
+// than once only counts the size of its latest version.
+func (b *MemoryBuilder) addFileSize(filePath string, size int64) error {
+	err := b.checkFileSize(filePath, size)
+	if err != nil {
+		return err
+	}
+
+	prevSize := b.fileSizes[filePath]
+
+	totalSize := b.totalSize - prevSize + size
+	if b.maxSize > 0 && totalSize > b.maxSize {
+		return fmt.Errorf("including %s makes the vault %s, larger than the maximum size of %s",
+			filePath, humanize.Bytes(uint64(totalSize)), humanize.Bytes(uint64(b.maxSize)))
+	}
+
+	var matched []*sizeBudget
+	for _, budget := range b.budgets {
+		match, err := matchPathGlob(budget.glob, filePath)
+		if err != nil {
+			return err
+		}
+		if !match {
+			continue
+		}
+
+		used := budget.used - prevSize + size
+		if used > budget.size {
+			return fmt.Errorf("including %s makes files matching %s %s, larger than their budget of %s",
+				filePath, budget.glob, humanize.Bytes(uint64(used)), humanize.Bytes(uint64(budget.size)))
+		}
+		matched = append(matched, budget)
+	}
+
+	// Only update the totals once the file is known to fit in all of them.
+	for _, budget := range matched {
+		budget.used += size - prevSize
+	}
+	b.totalSize = totalSize
+	b.fileSizes[filePath] = size
+
+	return nil
+}
+
+// SizeReport summarizes the size of the files in a vault.
+type SizeReport struct {
+	// Files is the number of files in the vault.
+	Files int `json:"files"`
+	// RawSize is the total size of the files in the vault.
+	RawSize int64 `json:"rawSize"`
+	// CompressedSize is the size of the vault's binary representation.
+	CompressedSize int64 `json:"compressedSize"`
+	// Largest are the largest files in the vault, largest first.
+	Largest []FileSizeReport `json:"largest"`
+	// Extensions are the sizes of the files with each extension, largest first.
+	Extensions []ExtensionSizeReport `json:"extensions"`
+}
+
+// FileSizeReport is the size of a single file in a vault.
+type FileSizeReport struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	// CompressedSize is the size of the file when compressed on its own.
+	CompressedSize int64 `json:"compressedSize"`
+}
+
+// ExtensionSizeReport is the size of the files with an extension in a vault.
+type ExtensionSizeReport struct {
+	// Extension is the lower-case file extension, including the leading dot, or an
+	// empty string for files without an extension.
+	Extension      string `json:"extension"`
+	Files          int    `json:"files"`
+	RawSize        int64  `json:"rawSize"`
+	CompressedSize int64  `json:"compressedSize"`
+	// Ratio is the compressed size as a fraction of the raw size.
+	Ratio float64 `json:"ratio"`
+}
+
+// Report returns a summary of the size of the vault being built, including up to
+// topN of the largest files. Compressed sizes for files and extensions are found by
+// compressing each file on its own at the builder's compression level.
+func (b *MemoryBuilder) Report(topN int) (*SizeReport, error) {
+	vaultData, err := b.marshalVault()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &SizeReport{
+		Files:          len(b.included),
+		RawSize:        b.totalSize,
+		CompressedSize: int64(len(vaultData)),
+	}
+
+	extensions := map[string]*ExtensionSizeReport{}
+	var files []FileSizeReport
+	for filePath := range b.included {
+		data, err := b.v.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		compressedSize, err := b.compressedSize(data)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, FileSizeReport{
+			Path:           filePath,
+			Size:           int64(len(data)),
+			CompressedSize: compressedSize,
+		})
+
+		ext := strings.ToLower(path.Ext(filePath))
+		extReport, ok := extensions[ext]
+		if !ok {
+			extReport = &ExtensionSizeReport{Extension: ext}
+			extensions[ext] = extReport
+		}
+		extReport.Files++
+		extReport.RawSize += int64(len(data))
+		extReport.CompressedSize += compressedSize
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Size == files[j].Size {
+			return files[i].Path < files[j].Path
+		}
+		return files[i].Size > files[j].Size
+	})
+	if topN >= 0 && len(files) > topN {
+		files = files[:topN]
+	}
+	r.Largest = files
+
+	for _, extReport := range extensions {
+		if extReport.RawSize > 0 {
+			extReport.Ratio = float64(extReport.CompressedSize) / float64(extReport.RawSize)
+		}
+		r.Extensions = append(r.Extensions, *extReport)
+	}
+	sort.Slice(r.Extensions, func(i, j int) bool {
+		if r.Extensions[i].RawSize == r.Extensions[j].RawSize {
+			return r.Extensions[i].Extension < r.Extensions[j].Extension
+		}
+		return r.Extensions[i].RawSize > r.Extensions[j].RawSize
+	})
+
+	return r, nil
+}
+
+func (b *MemoryBuilder) compressedSize(data []byte) (int64, error) {
+	counter := &countingWriter{w: ioutil.Discard}
+	gzW, err := gzip.NewWriterLevel(counter, b.compressionLevel)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = gzW.Write(data)
+	if err != nil {
+		return 0, err
+	}
+	err = gzW.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	return counter.n, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// WriteText writes the report in a human readable form.
+func (r *SizeReport) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Files:\t%d\n", r.Files)
+	fmt.Fprintf(tw, "Raw size:\t%s\n", humanize.Bytes(uint64(r.RawSize)))
+	fmt.Fprintf(tw, "Compressed size:\t%s\n", humanize.Bytes(uint64(r.CompressedSize)))
+
+	if len(r.Largest) > 0 {
+		fmt.Fprintf(tw, "\nLargest files:\n")
+		for _, f := range r.Largest {
+			fmt.Fprintf(tw, "  %s\t%s\t%s compressed\n", f.Path,
+				humanize.Bytes(uint64(f.Size)), humanize.Bytes(uint64(f.CompressedSize)))
+		}
+	}
+
+	if len(r.Extensions) > 0 {
+		fmt.Fprintf(tw, "\nBy extension:\n")
+		for _, ext := range r.Extensions {
+			name := ext.Extension
+			if name == "" {
+				name = "(none)"
+			}
+			files := "files"
+			if ext.Files == 1 {
+				files = "file"
+			}
+			fmt.Fprintf(tw, "  %s\t%d %s\t%s\t%s compressed\t%.0f%%\n", name, ext.Files, files,
+				humanize.Bytes(uint64(ext.RawSize)), humanize.Bytes(uint64(ext.CompressedSize)), ext.Ratio*100)
+		}
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,756 @@
+package goblin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFiles(t *testing.T, rootPath string, files map[string]string) {
+	t.Helper()
+
+	for name, data := range files {
+		fullPath := filepath.Join(rootPath, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, ioutil.WriteFile(fullPath, []byte(data), 0644))
+	}
+}
+
+func TestMemoryBuilderPrecompress(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	writeTestFiles(t, td, map[string]string{
+		"app.js":   strings.Repeat("console.log('app');\n", 100),
+		"tiny.css": "a{}",
+		"logo.png": strings.Repeat("png", 100),
+	})
+
+	b := NewMemoryBuilder(MemoryBuilderPrecompress(EncodingGzip, EncodingDeflate))
+	require.NoError(t, b.Include(td, []string{"*"}))
+
+	encodings, err := b.v.FileEncodings("app.js")
+	require.NoError(t, err)
+	assert.Equal(t, []string{EncodingDeflate, EncodingGzip}, encodings)
+
+	// Compressing tiny files makes them larger, so no variant should be stored
+	encodings, err = b.v.FileEncodings("tiny.css")
+	require.NoError(t, err)
+	assert.Empty(t, encodings)
+
+	encodings, err = b.v.FileEncodings("logo.png")
+	require.NoError(t, err)
+	assert.Empty(t, encodings)
+}
+
+func TestMemoryBuilderFingerprint(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	writeTestFiles(t, td, map[string]string{
+		"app.js":     "console.log('app');",
+		"index.html": "<html></html>",
+	})
+
+	b := NewMemoryBuilder(MemoryBuilderFingerprint("*.js"))
+	require.NoError(t, b.Include(td, []string{"*"}))
+
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, b.WriteBinary(buf))
+
+	v, err := LoadMemoryVault(buf.Bytes())
+	require.NoError(t, err)
+
+	fp, err := LoadFingerprints(v)
+	require.NoError(t, err)
+
+	fpPath, ok := fp.Lookup("app.js")
+	require.True(t, ok)
+	assert.Regexp(t, `^app\.[0-9a-f]{8}\.js$`, fpPath)
+
+	data, err := v.ReadFile(fpPath)
+	require.NoError(t, err)
+	assert.Equal(t, "console.log('app');", string(data))
+
+	_, ok = fp.Lookup("index.html")
+	assert.False(t, ok)
+}
+
+func TestAccessorName(t *testing.T) {
+	assert.Equal(t, "IndexHTML", accessorName("index.html"))
+	assert.Equal(t, "StaticJSAppJS", accessorName("static/js/app.js"))
+	assert.Equal(t, "ImagesLogo2xPNG", accessorName("images/logo@2x.png"))
+	assert.Equal(t, "MyFileTxt2", accessorName("my-file_txt2"))
+}
+
+func TestMemoryBuilderAccessors(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	writeTestFiles(t, td, map[string]string{
+		"index.html": "<html></html>",
+		"app.js":     "console.log('app');",
+		"app-js":     "collides with app.js",
+	})
+
+	t.Run("unexported", func(t *testing.T) {
+		b := NewMemoryBuilder(MemoryBuilderAccessors(true))
+		require.NoError(t, b.Include(td, []string{"*"}))
+
+		buf := bytes.NewBuffer(nil)
+		require.NoError(t, b.WriteLoader("assets", "assets", buf))
+
+		code := buf.String()
+		assert.Contains(t, code, `assetsAppJS = "app-js"`)
+		assert.Contains(t, code, `assetsAppJS2 = "app.js"`)
+		assert.Contains(t, code, `assetsIndexHTML = "index.html"`)
+		assert.Contains(t, code, "func assetsPaths() []string {\n\treturn []string{\n\t\tassetsAppJS,\n\t\tassetsAppJS2,\n\t\tassetsIndexHTML,\n\t}\n}")
+
+		_, err := parser.ParseFile(token.NewFileSet(), "goblin_assets.go", code, 0)
+		require.NoError(t, err)
+	})
+
+	t.Run("exported", func(t *testing.T) {
+		b := NewMemoryBuilder(MemoryBuilderAccessors(true), MemoryBuilderExportLoader(true))
+		require.NoError(t, b.Include(td, []string{"*.html"}))
+
+		buf := bytes.NewBuffer(nil)
+		require.NoError(t, b.WriteLoader("assets", "assets", buf))
+
+		code := buf.String()
+		assert.Contains(t, code, "// AssetsIndexHTML is the path of index.html in the assets vault.\n")
+		assert.Contains(t, code, `AssetsIndexHTML = "index.html"`)
+		assert.Contains(t, code, "func AssetsPaths() []string")
+		assert.NotContains(t, code, "AssetsAppJS")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		b := NewMemoryBuilder()
+		require.NoError(t, b.Include(td, []string{"*"}))
+
+		buf := bytes.NewBuffer(nil)
+		require.NoError(t, b.WriteLoader("assets", "assets", buf))
+		assert.NotContains(t, buf.String(), "assetsPaths")
+	})
+}
+
+func TestStringLiteralChunks(t *testing.T) {
+	data := make([]byte, stringLiteralChunkSize*2+10)
+	for idx := range data {
+		data[idx] = byte(idx)
+	}
+
+	code := jen.Var().Id("x").Op("=").Add(stringLiteralChunks(data)...)
+	expr, err := parser.ParseExpr(strings.TrimPrefix(fmt.Sprintf("%#v", code), "var x = "))
+	require.NoError(t, err)
+
+	var lits []string
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.BasicLit); ok {
+			val, err := strconv.Unquote(lit.Value)
+			require.NoError(t, err)
+			lits = append(lits, val)
+		}
+		return true
+	})
+	assert.Len(t, lits, 3)
+	assert.Equal(t, data, []byte(strings.Join(lits, "")))
+
+	assert.Equal(t, `""`, fmt.Sprintf("%#v", jen.Add(stringLiteralChunks(nil)...)))
+}
+
+func TestMemoryBuilderLoaderFormat(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	writeTestFiles(t, td, map[string]string{
+		"index.html": "<html></html>",
+	})
+
+	b := NewMemoryBuilder(MemoryBuilderLoaderFormat(LoaderFormatString))
+	require.NoError(t, b.Include(td, []string{"*"}))
+
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, b.WriteLoader("assets", "assets", buf))
+
+	code := buf.String()
+	assert.Contains(t, code, "return goblin.LoadMemoryVault([]byte(goblinMemoryVaultXassets))")
+	assert.Contains(t, code, `const goblinMemoryVaultXassets = "\x1f\x8b`)
+
+	_, err = parser.ParseFile(token.NewFileSet(), "goblin_assets.go", code, 0)
+	require.NoError(t, err)
+
+	b = NewMemoryBuilder(MemoryBuilderLoaderFormat(LoaderFormat(99)))
+	err = b.WriteLoader("assets", "assets", bytes.NewBuffer(nil))
+	assert.EqualError(t, err, "unknown loader format: 99")
+}
+
+func TestMemoryBuilderWriteEmbedLoader(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	writeTestFiles(t, td, map[string]string{
+		"index.html": "<html></html>",
+	})
+
+	t.Run("write code and binary", func(t *testing.T) {
+		b := NewMemoryBuilder(MemoryBuilderExportLoader(true))
+		require.NoError(t, b.Include(td, []string{"*"}))
+
+		codeBuf := bytes.NewBuffer(nil)
+		binBuf := bytes.NewBuffer(nil)
+		require.NoError(t, b.WriteEmbedLoader("assets", "assets", "goblin_assets.bin", codeBuf, binBuf))
+
+		code := codeBuf.String()
+		assert.Contains(t, code, "\t_ \"embed\"\n")
+		assert.Contains(t, code, "//go:embed goblin_assets.bin\nvar goblinMemoryVaultXassets []byte\n")
+		assert.Contains(t, code, "func LoadVaultAssets() (goblin.Vault, error) {\n"+
+			"\treturn goblin.LoadMemoryVault(goblinMemoryVaultXassets)\n}")
+
+		_, err := parser.ParseFile(token.NewFileSet(), "goblin_assets.go", code, 0)
+		require.NoError(t, err)
+
+		v, err := LoadMemoryVault(binBuf.Bytes())
+		require.NoError(t, err)
+		data, err := v.ReadFile("index.html")
+		require.NoError(t, err)
+		assert.Equal(t, "<html></html>", string(data))
+	})
+
+	t.Run("quote names with spaces", func(t *testing.T) {
+		b := NewMemoryBuilder()
+		require.NoError(t, b.Include(td, []string{"*"}))
+
+		codeBuf := bytes.NewBuffer(nil)
+		require.NoError(t, b.WriteEmbedLoader("assets", "assets", "my assets.bin", codeBuf, ioutil.Discard))
+		assert.Contains(t, codeBuf.String(), "//go:embed \"my assets.bin\"\n")
+	})
+
+	t.Run("binary name with path", func(t *testing.T) {
+		b := NewMemoryBuilder()
+		err := b.WriteEmbedLoader("assets", "assets", "data/assets.bin", ioutil.Discard, ioutil.Discard)
+		assert.EqualError(t, err, "embedded binary name must be a file name: data/assets.bin")
+	})
+}
+
+func TestMemoryBuilderExclude(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	writeTestFiles(t, td, map[string]string{
+		".gitignore":                 "# build output\n*.map\n!vendor.js.map\n",
+		".goblinignore":              "/drafts\n",
+		"app.js":                     "app",
+		"app.js.map":                 "map",
+		"vendor.js.map":              "vendor map",
+		"index.html.swp":             "swap",
+		"drafts/post.html":           "draft",
+		"pages/drafts/post.html":     "not a draft",
+		"node_modules/left-pad/a.js": "pad",
+		"static/app.css":             "css",
+	})
+
+	includes := []string{"*.*", "*/*.*", "*/*/*.*"}
+	vaultFiles := func(b *MemoryBuilder) []string {
+		var files []string
+		err := Walk(b.v, ".", func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				files = append(files, path)
+			}
+			return err
+		})
+		require.NoError(t, err)
+		return files
+	}
+
+	t.Run("exclude patterns", func(t *testing.T) {
+		b := NewMemoryBuilder(MemoryBuilderExclude("node_modules", "*.swp", ".*ignore", "*/*.html"))
+		require.NoError(t, b.Include(td, includes))
+
+		assert.Equal(t, []string{
+			"app.js",
+			"app.js.map",
+			"pages/drafts/post.html",
+			"static/app.css",
+			"vendor.js.map",
+		}, vaultFiles(b))
+	})
+
+	t.Run("ignore files", func(t *testing.T) {
+		b := NewMemoryBuilder(
+			MemoryBuilderIgnoreFiles(".gitignore", ".goblinignore", ".missingignore"),
+			MemoryBuilderExclude("node_modules", ".*", "*.swp", "!.gitignore"),
+		)
+		require.NoError(t, b.Include(td, includes))
+
+		assert.Equal(t, []string{
+			".gitignore",
+			"app.js",
+			"pages/drafts/post.html",
+			"static/app.css",
+			"vendor.js.map",
+		}, vaultFiles(b))
+	})
+}
+
+func TestMemoryBuilderIncludeDirs(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	writeTestFiles(t, td, map[string]string{
+		"index.html":                  "index",
+		"static/app.js":               "app",
+		"static/css/site.css":         "css",
+		"static/css/vendor/reset.css": "reset",
+		"static/node_modules/pad.js":  "pad",
+		"templates/page.html":         "page",
+	})
+
+	vaultFiles := func(b *MemoryBuilder) []string {
+		var files []string
+		err := Walk(b.v, ".", func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				files = append(files, path)
+			}
+			return err
+		})
+		require.NoError(t, err)
+		return files
+	}
+
+	t.Run("glob matching directories", func(t *testing.T) {
+		b := NewMemoryBuilder(MemoryBuilderExclude("node_modules"))
+		require.NoError(t, b.Include(td, []string{"*"}))
+
+		assert.Equal(t, []string{
+			"index.html",
+			"static/app.js",
+			"static/css/site.css",
+			"static/css/vendor/reset.css",
+			"templates/page.html",
+		}, vaultFiles(b))
+	})
+
+	t.Run("nested glob paths are relative to root", func(t *testing.T) {
+		b := NewMemoryBuilder()
+		require.NoError(t, b.Include(td, []string{"static/css/*.css", "static/*/vendor"}))
+
+		assert.Equal(t, []string{
+			"static/css/site.css",
+			"static/css/vendor/reset.css",
+		}, vaultFiles(b))
+	})
+
+	t.Run("empty root paths are relative to the working directory", func(t *testing.T) {
+		wd, err := os.Getwd()
+		require.NoError(t, err)
+		require.NoError(t, os.Chdir(td))
+		defer os.Chdir(wd)
+
+		// Paths used to be relative to the glob's directory when the root was empty,
+		// which stored static/app.js as app.js.
+		b := NewMemoryBuilder()
+		require.NoError(t, b.Include("", []string{"static/*.js", "templates/*.html"}))
+
+		assert.Equal(t, []string{
+			"static/app.js",
+			"templates/page.html",
+		}, vaultFiles(b))
+	})
+
+	t.Run("include dirs", func(t *testing.T) {
+		b := NewMemoryBuilder()
+		require.NoError(t, b.IncludeDirs(td, []string{"static/css", "templates"}))
+
+		assert.Equal(t, []string{
+			"static/css/site.css",
+			"static/css/vendor/reset.css",
+			"templates/page.html",
+		}, vaultFiles(b))
+	})
+
+	t.Run("include root dir", func(t *testing.T) {
+		b := NewMemoryBuilder(MemoryBuilderExclude("static/"))
+		require.NoError(t, b.IncludeDirs(td, []string{"."}))
+
+		assert.Equal(t, []string{
+			"index.html",
+			"templates/page.html",
+		}, vaultFiles(b))
+	})
+
+	t.Run("max depth", func(t *testing.T) {
+		b := NewMemoryBuilder(MemoryBuilderMaxDepth(2))
+		require.NoError(t, b.IncludeDirs(td, []string{"static"}))
+
+		assert.Equal(t, []string{
+			"static/app.js",
+			"static/css/site.css",
+			"static/node_modules/pad.js",
+		}, vaultFiles(b))
+	})
+
+	t.Run("include dir errors", func(t *testing.T) {
+		b := NewMemoryBuilder()
+
+		err := b.IncludeDirs(td, []string{"index.html"})
+		assert.EqualError(t, err, "not a directory: "+filepath.Join(td, "index.html"))
+
+		err = b.IncludeDirs(td, []string{"missing"})
+		assert.True(t, os.IsNotExist(err))
+
+		err = b.IncludeDirs(td, []string{"../"})
+		assert.EqualError(t, err, ".. cannot be used in include paths")
+	})
+
+	t.Run("invalid include glob", func(t *testing.T) {
+		b := NewMemoryBuilder()
+
+		err := b.Include(td, []string{"static/["})
+		assert.True(t, errors.Is(err, filepath.ErrBadPattern))
+		assert.EqualError(t, err, "invalid include glob "+filepath.Join(td, "static/[")+": syntax error in pattern")
+	})
+}
+
+func TestVaultRelPath(t *testing.T) {
+	relPath, err := vaultRelPath("", filepath.Join("web", "static", "app.js"))
+	require.NoError(t, err)
+	assert.Equal(t, "web/static/app.js", relPath)
+
+	relPath, err = vaultRelPath("web", filepath.Join("web", "static", "app.js"))
+	require.NoError(t, err)
+	assert.Equal(t, "static/app.js", relPath)
+
+	relPath, err = vaultRelPath("web", "web")
+	require.NoError(t, err)
+	assert.Equal(t, ".", relPath)
+
+	_, err = vaultRelPath("web", "other")
+	assert.EqualError(t, err, "other is not in the include root web")
+}
+
+func TestIncludeOptionsMapPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []IncludeOption
+		relPath  string
+		expected string
+		err      string
+	}{
+		{"no options", nil, "js/app.js", "js/app.js", ""},
+		{"prefix", []IncludeOption{IncludePrefix("/static/")}, "js/app.js", "static/js/app.js", ""},
+		{"nested prefix", []IncludeOption{IncludePrefix("public/static")}, "app.js", "public/static/app.js", ""},
+		{"strip segments", []IncludeOption{IncludeStripSegments(1)}, "dist/js/app.js", "js/app.js", ""},
+		{"strip too many", []IncludeOption{IncludeStripSegments(2)}, "js/app.js", "", "cannot strip 2 segments from js/app.js"},
+		{
+			"rename",
+			[]IncludeOption{IncludeRename(regexp.MustCompile(`\.min\.(js|css)$`), ".$1")},
+			"js/app.min.js", "js/app.js", "",
+		},
+		{
+			"strip, rename then prefix",
+			[]IncludeOption{
+				IncludePrefix("static"),
+				IncludeRename(regexp.MustCompile(`^v1/`), "legacy/"),
+				IncludeStripSegments(1),
+			},
+			"dist/v1/app.js", "static/legacy/app.js", "",
+		},
+		{
+			"rename to invalid path",
+			[]IncludeOption{IncludeRename(regexp.MustCompile(`^.*$`), "../x")},
+			"app.js", "", "app.js was mapped to an invalid path: ../x",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath, err := newIncludeOptions(tt.opts...).mapPath(tt.relPath)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, filePath)
+		})
+	}
+}
+
+func TestMemoryBuilderIncludeMapping(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	writeTestFiles(t, td, map[string]string{
+		"frontend/dist/index.html":   "index",
+		"frontend/dist/js/app.js":    "app",
+		"frontend/dist/js/app.map":   "map",
+		"db/migrations/001_up.sql":   "up",
+		"db/migrations/001_down.sql": "down",
+	})
+
+	b := NewMemoryBuilder(MemoryBuilderExclude("*.map"))
+	require.NoError(t, b.IncludeDirs(filepath.Join(td, "frontend", "dist"), []string{"."}, IncludePrefix("static")))
+	require.NoError(t, b.Include(td, []string{"db/migrations/*_up.sql"},
+		IncludeStripSegments(1),
+		IncludeRename(regexp.MustCompile(`_up\.sql$`), ".sql"),
+	))
+
+	var files []string
+	err = Walk(b.v, ".", func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			files = append(files, path)
+		}
+		return err
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"migrations/001.sql",
+		"static/index.html",
+		"static/js/app.js",
+	}, files)
+}
+
+func TestMemoryBuilderReproducible(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	writeTestFiles(t, td, map[string]string{
+		"index.html":    "index",
+		"static/app.js": strings.Repeat("console.log('app');\n", 100),
+	})
+
+	clampTime := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	build := func(modTime time.Time) []byte {
+		for _, name := range []string{"index.html", "static/app.js"} {
+			require.NoError(t, os.Chtimes(filepath.Join(td, name), modTime, modTime))
+		}
+
+		b := NewMemoryBuilder(
+			MemoryBuilderClampModTime(clampTime),
+			MemoryBuilderPrecompress(EncodingGzip),
+			MemoryBuilderFingerprint("*.js"),
+		)
+		require.NoError(t, b.Include(td, []string{"*"}))
+
+		buf := bytes.NewBuffer(nil)
+		require.NoError(t, b.WriteLoader("assets", "assets", buf))
+		return buf.Bytes()
+	}
+
+	code1 := build(time.Now())
+	code2 := build(time.Now().Add(time.Hour))
+	assert.Equal(t, code1, code2)
+
+	// Files modified before the clamp time keep their modified time
+	b := NewMemoryBuilder(MemoryBuilderClampModTime(clampTime))
+	oldTime := clampTime.Add(-time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(td, "index.html"), oldTime, oldTime))
+	require.NoError(t, b.Include(td, []string{"*"}))
+
+	fInfo, err := b.v.Stat("index.html")
+	require.NoError(t, err)
+	assert.True(t, oldTime.Equal(fInfo.ModTime()))
+
+	fInfo, err = b.v.Stat("static/app.js")
+	require.NoError(t, err)
+	assert.True(t, clampTime.Equal(fInfo.ModTime()))
+}
+
+func TestSourceDateEpoch(t *testing.T) {
+	defer os.Unsetenv(sourceDateEpochEnv)
+
+	os.Unsetenv(sourceDateEpochEnv)
+	epoch, err := SourceDateEpoch()
+	require.NoError(t, err)
+	assert.True(t, epoch.IsZero())
+
+	os.Setenv(sourceDateEpochEnv, "1591012800")
+	epoch, err = SourceDateEpoch()
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC), epoch)
+
+	os.Setenv(sourceDateEpochEnv, "soon")
+	_, err = SourceDateEpoch()
+	assert.EqualError(t, err, `invalid SOURCE_DATE_EPOCH soon: strconv.ParseInt: parsing "soon": invalid syntax`)
+}
+
+func TestMemoryBuilderInputs(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	writeTestFiles(t, td, map[string]string{
+		"index.html":         "index",
+		"static/app.js":      "app",
+		"static/app.js.map":  "map",
+		"templates/base.tpl": "base",
+	})
+
+	b := NewMemoryBuilder(
+		MemoryBuilderExclude("*.map"),
+		MemoryBuilderIgnoreFiles(".goblinignore"),
+	)
+	require.NoError(t, b.Include(td, []string{"index.html", "templates/*.tpl"}))
+	require.NoError(t, b.IncludeDirs(td, []string{"static"}))
+
+	assert.Equal(t, []string{
+		td,
+		filepath.Join(td, ".goblinignore"),
+		filepath.Join(td, "index.html"),
+		filepath.Join(td, "static"),
+		filepath.Join(td, "static", "app.js"),
+		filepath.Join(td, "templates"),
+		filepath.Join(td, "templates", "base.tpl"),
+	}, b.Inputs())
+}
+
+func TestGlobDir(t *testing.T) {
+	assert.Equal(t, "root", globDir("root/index.html"))
+	assert.Equal(t, filepath.Join("root", "static"), globDir(filepath.Join("root", "static", "*.js")))
+	assert.Equal(t, "root", globDir(filepath.Join("root", "*", "index.html")))
+}
+
+func TestMemoryBuilderSizeLimits(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	writeTestFiles(t, td, map[string]string{
+		"index.html":        strings.Repeat("a", 100),
+		"static/app.js":     strings.Repeat("b", 300),
+		"static/logo.png":   strings.Repeat("c", 200),
+		"static/video.webm": strings.Repeat("d", 2000),
+	})
+
+	tests := []struct {
+		name string
+		opts []MemoryBuilderOption
+		err  string
+	}{
+		{
+			name: "within limits",
+			opts: []MemoryBuilderOption{
+				MemoryBuilderMaxSize(2600),
+				MemoryBuilderMaxFileSize(2000),
+				MemoryBuilderSizeBudget("*.png", 200),
+			},
+		},
+		{
+			name: "max file size",
+			opts: []MemoryBuilderOption{MemoryBuilderMaxFileSize(1000)},
+			err:  "static/video.webm is 2.0 kB, larger than the maximum file size of 1.0 kB",
+		},
+		{
+			name: "max size",
+			opts: []MemoryBuilderOption{MemoryBuilderMaxSize(500)},
+			err:  "including static/logo.png makes the vault 600 B, larger than the maximum size of 500 B",
+		},
+		{
+			name: "size budget",
+			opts: []MemoryBuilderOption{MemoryBuilderSizeBudget("static/*", 400)},
+			err:  "including static/logo.png makes files matching static/* 500 B, larger than their budget of 400 B",
+		},
+		{
+			name: "max file size after transform",
+			opts: []MemoryBuilderOption{
+				MemoryBuilderMaxFileSize(1000),
+				MemoryBuilderTransform("index.html", TransformerFunc(func(filePath string, data []byte) ([]byte, error) {
+					return bytes.Repeat(data, 20), nil
+				})),
+			},
+			err: "index.html is 2.0 kB, larger than the maximum file size of 1.0 kB",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewMemoryBuilder(tt.opts...)
+			err := b.Include(td, []string{"index.html", "static/app.js", "static/logo.png", "static/video.webm"})
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+
+	t.Run("included twice", func(t *testing.T) {
+		b := NewMemoryBuilder(MemoryBuilderMaxSize(100))
+		require.NoError(t, b.Include(td, []string{"index.html"}))
+		require.NoError(t, b.Include(td, []string{"index.html"}))
+		assert.Equal(t, int64(100), b.totalSize)
+	})
+}
+
+func TestMemoryBuilderReport(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	writeTestFiles(t, td, map[string]string{
+		"index.html":    strings.Repeat("<p>index</p>", 100),
+		"about.html":    strings.Repeat("<p>about</p>", 10),
+		"static/app.js": strings.Repeat("console.log('app');", 200),
+		"LICENSE":       "MIT",
+	})
+
+	b := NewMemoryBuilder(MemoryBuilderFingerprint("*.js"))
+	require.NoError(t, b.Include(td, []string{"*"}))
+
+	r, err := b.Report(2)
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, r.Files)
+	assert.Equal(t, int64(1200+120+3800+3), r.RawSize)
+
+	vaultBuf := bytes.NewBuffer(nil)
+	require.NoError(t, b.WriteBinary(vaultBuf))
+	assert.Equal(t, int64(vaultBuf.Len()), r.CompressedSize)
+
+	require.Len(t, r.Largest, 2)
+	assert.Equal(t, "static/app.js", r.Largest[0].Path)
+	assert.Equal(t, int64(3800), r.Largest[0].Size)
+	assert.True(t, r.Largest[0].CompressedSize < r.Largest[0].Size)
+	assert.Equal(t, "index.html", r.Largest[1].Path)
+
+	require.Len(t, r.Extensions, 3)
+	assert.Equal(t, ".js", r.Extensions[0].Extension)
+	assert.Equal(t, ".html", r.Extensions[1].Extension)
+	assert.Equal(t, 2, r.Extensions[1].Files)
+	assert.Equal(t, int64(1320), r.Extensions[1].RawSize)
+	assert.InDelta(t, float64(r.Extensions[1].CompressedSize)/1320, r.Extensions[1].Ratio, 0.0001)
+	assert.Equal(t, "", r.Extensions[2].Extension)
+
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, r.WriteText(buf))
+	text := buf.String()
+	assert.Contains(t, text, "Files:            4\n")
+	assert.Contains(t, text, "Raw size:         5.1 kB\n")
+	assert.Contains(t, text, "  static/app.js")
+	assert.Contains(t, text, "  (none)")
+}
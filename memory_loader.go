@@ -1,5 +1,20 @@
 package goblin
 
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	embedDirective = "//go:embed "
+)
+
 // LoadMemoryOption is an option used when loading an in-memory vault.
 type LoadMemoryOption func(*loadMemoryOptions)
 
@@ -24,3 +39,123 @@ func LoadMemoryVault(vaultData []byte, opts ...LoadMemoryOption) (Vault, error)
 
 	return v, nil
 }
+
+// ReadLoaderData reads the binary representation of the vault with the provided name
+// from a code file generated by MemoryBuilder.WriteLoader or
+// MemoryBuilder.WriteEmbedLoader. For a go:embed loader, the binary file next to the
+// code file is read. The data can be loaded with LoadMemoryVault.
+func ReadLoaderData(codePath string, vaultName string) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, codePath, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	fullVaultName := makeMemoryVaultName(vaultName)
+	for _, decl := range f.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || (genDecl.Tok != token.VAR && genDecl.Tok != token.CONST) {
+			continue
+		}
+
+		for _, spec := range genDecl.Specs {
+			valueSpec, ok := spec.(*ast.ValueSpec)
+			if !ok || len(valueSpec.Names) != 1 || valueSpec.Names[0].Name != fullVaultName {
+				continue
+			}
+
+			if len(valueSpec.Values) == 0 {
+				return readEmbeddedLoaderData(codePath, genDecl.Doc)
+			}
+			if len(valueSpec.Values) != 1 {
+				break
+			}
+
+			data, err := loaderExprData(valueSpec.Values[0])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", fset.Position(valueSpec.Pos()), err)
+			}
+
+			return data, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s: vault %s not found", codePath, vaultName)
+}
+
+// loaderExprData returns the data in a []byte literal or a string literal, which
+// may be split into concatenated chunks.
+func loaderExprData(expr ast.Expr) ([]byte, error) {
+	switch e := expr.(type) {
+	case *ast.CompositeLit:
+		data := make([]byte, 0, len(e.Elts))
+		for _, elt := range e.Elts {
+			// Elements are written as byte(0x00) conversions.
+			if call, ok := elt.(*ast.CallExpr); ok && len(call.Args) == 1 {
+				elt = call.Args[0]
+			}
+
+			lit, ok := elt.(*ast.BasicLit)
+			if !ok || lit.Kind != token.INT {
+				return nil, fmt.Errorf("unexpected vault data element")
+			}
+
+			b, err := strconv.ParseUint(lit.Value, 0, 8)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, byte(b))
+		}
+		return data, nil
+
+	case *ast.BasicLit:
+		if e.Kind != token.STRING {
+			return nil, fmt.Errorf("unexpected vault data literal")
+		}
+
+		s, err := strconv.Unquote(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(s), nil
+
+	case *ast.BinaryExpr:
+		if e.Op != token.ADD {
+			return nil, fmt.Errorf("unexpected vault data operator %s", e.Op)
+		}
+
+		left, err := loaderExprData(e.X)
+		if err != nil {
+			return nil, err
+		}
+		right, err := loaderExprData(e.Y)
+		if err != nil {
+			return nil, err
+		}
+		return append(left, right...), nil
+
+	default:
+		return nil, fmt.Errorf("unexpected vault data expression")
+	}
+}
+
+// readEmbeddedLoaderData reads the file named by the //go:embed directive in the
+// comments of a go:embed loader's variable.
+func readEmbeddedLoaderData(codePath string, doc *ast.CommentGroup) ([]byte, error) {
+	if doc != nil {
+		for _, comment := range doc.List {
+			if !strings.HasPrefix(comment.Text, embedDirective) {
+				continue
+			}
+
+			embedName := strings.TrimSpace(strings.TrimPrefix(comment.Text, embedDirective))
+			if unquoted, err := strconv.Unquote(embedName); err == nil {
+				embedName = unquoted
+			}
+
+			return ioutil.ReadFile(filepath.Join(filepath.Dir(codePath), filepath.FromSlash(embedName)))
+		}
+	}
+
+	return nil, fmt.Errorf("%s: vault data has no value or %s directive", codePath, embedDirective)
+}
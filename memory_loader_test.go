@@ -0,0 +1,77 @@
+package goblin
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadLoaderData(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	writeTestFiles(t, td, map[string]string{
+		"in/index.html": "<html></html>",
+	})
+
+	b := NewMemoryBuilder()
+	require.NoError(t, b.Include(filepath.Join(td, "in"), []string{"*"}))
+
+	binBuf := bytes.NewBuffer(nil)
+	require.NoError(t, b.WriteBinary(binBuf))
+
+	assertLoaderData := func(t *testing.T, codePath string) {
+		t.Helper()
+
+		data, err := ReadLoaderData(codePath, "assets")
+		require.NoError(t, err)
+		assert.Equal(t, binBuf.Bytes(), data)
+	}
+
+	t.Run("bytes format", func(t *testing.T) {
+		codeBuf := bytes.NewBuffer(nil)
+		require.NoError(t, b.WriteLoader("assets", "assets", codeBuf))
+
+		codePath := filepath.Join(td, "bytes.go")
+		require.NoError(t, ioutil.WriteFile(codePath, codeBuf.Bytes(), 0644))
+		assertLoaderData(t, codePath)
+	})
+
+	t.Run("string format", func(t *testing.T) {
+		b.loaderFormat = LoaderFormatString
+		defer func() { b.loaderFormat = LoaderFormatBytes }()
+
+		codeBuf := bytes.NewBuffer(nil)
+		require.NoError(t, b.WriteLoader("assets", "assets", codeBuf))
+
+		codePath := filepath.Join(td, "string.go")
+		require.NoError(t, ioutil.WriteFile(codePath, codeBuf.Bytes(), 0644))
+		assertLoaderData(t, codePath)
+	})
+
+	t.Run("embed", func(t *testing.T) {
+		codePath := filepath.Join(td, "embed.go")
+		codeF, err := os.Create(codePath)
+		require.NoError(t, err)
+		defer codeF.Close()
+		binF, err := os.Create(filepath.Join(td, "my assets.bin"))
+		require.NoError(t, err)
+		defer binF.Close()
+
+		require.NoError(t, b.WriteEmbedLoader("assets", "assets", "my assets.bin", codeF, binF))
+		require.NoError(t, codeF.Close())
+		require.NoError(t, binF.Close())
+		assertLoaderData(t, codePath)
+	})
+
+	t.Run("vault not found", func(t *testing.T) {
+		_, err := ReadLoaderData(filepath.Join(td, "bytes.go"), "other")
+		assert.EqualError(t, err, filepath.Join(td, "bytes.go")+": vault other not found")
+	})
+}
@@ -210,10 +210,15 @@ func (omd *openMemoryDir) Read(data []byte) (int, error) {
 }
 
 type memoryFile struct {
-	fullPath string
-	name     string
-	modTime  time.Time
-	data     []byte
+	fullPath  string
+	name      string
+	modTime   time.Time
+	data      []byte
+	encodings map[string][]byte
+
+	// linkTarget is the path of the file this file shares its contents with,
+	// if it was created with MemoryVault.Link.
+	linkTarget string
 }
 
 var _ fsNode = &memoryFile{}
@@ -275,12 +280,17 @@ type openMemoryFile struct {
 }
 
 var _ File = &openMemoryFile{}
+var _ io.Seeker = &openMemoryFile{}
 
 func (omf *openMemoryFile) Read(buf []byte) (int, error) {
 	if omf.closed {
 		return 0, os.ErrClosed
 	}
 
+	if omf.curRead >= len(omf.data) {
+		return 0, io.EOF
+	}
+
 	bufLen := len(buf)
 	readLeft := len(omf.data) - omf.curRead
 	readLen := bufLen
@@ -298,6 +308,33 @@ func (omf *openMemoryFile) Read(buf []byte) (int, error) {
 	return readLen, readErr
 }
 
+func (omf *openMemoryFile) Seek(offset int64, whence int) (int64, error) {
+	if omf.closed {
+		return 0, os.ErrClosed
+	}
+
+	var newPos int64
+	switch whence {
+	case io.SeekStart:
+		newPos = offset
+	case io.SeekCurrent:
+		newPos = int64(omf.curRead) + offset
+	case io.SeekEnd:
+		newPos = int64(len(omf.data)) + offset
+	default:
+		return 0, fmt.Errorf("invalid whence: %d", whence)
+	}
+
+	if newPos < 0 {
+		return 0, fmt.Errorf("negative position: %d", newPos)
+	}
+
+	// Seeking past the end is allowed, reads will return io.EOF
+	omf.curRead = int(newPos)
+
+	return newPos, nil
+}
+
 func (omf *openMemoryFile) Close() error {
 	omf.closed = true
 	return nil
@@ -1,6 +1,8 @@
 package goblin
 
 import (
+	"io"
+	"io/ioutil"
 	"testing"
 	"time"
 
@@ -171,3 +173,53 @@ func TestMemoryFileGetNode(t *testing.T) {
 		assert.Nil(t, n)
 	})
 }
+
+func TestOpenMemoryFileSeek(t *testing.T) {
+	t.Run("seek and read", func(t *testing.T) {
+		f, err := newMemoryFile("file.txt", []byte("0123456789")).Open()
+		require.NoError(t, err)
+		seeker := f.(io.Seeker)
+
+		pos, err := seeker.Seek(4, io.SeekStart)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), pos)
+
+		buf := make([]byte, 2)
+		n, err := f.Read(buf)
+		require.NoError(t, err)
+		assert.Equal(t, "45", string(buf[:n]))
+
+		pos, err = seeker.Seek(-1, io.SeekCurrent)
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), pos)
+
+		pos, err = seeker.Seek(-2, io.SeekEnd)
+		require.NoError(t, err)
+		assert.Equal(t, int64(8), pos)
+
+		data, err := ioutil.ReadAll(f)
+		require.NoError(t, err)
+		assert.Equal(t, "89", string(data))
+	})
+
+	t.Run("seek past end", func(t *testing.T) {
+		f, err := newMemoryFile("file.txt", []byte("0123")).Open()
+		require.NoError(t, err)
+
+		pos, err := f.(io.Seeker).Seek(10, io.SeekStart)
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), pos)
+
+		n, err := f.Read(make([]byte, 4))
+		assert.Equal(t, 0, n)
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("negative position", func(t *testing.T) {
+		f, err := newMemoryFile("file.txt", []byte("0123")).Open()
+		require.NoError(t, err)
+
+		_, err = f.(io.Seeker).Seek(-1, io.SeekStart)
+		assert.Error(t, err)
+	})
+}
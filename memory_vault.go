@@ -36,9 +36,13 @@ func newMemoryVaultOptions() *memoryVaultOptions {
 // such as one embedded in a file.
 type MemoryVault struct {
 	root *memoryDir
+
+	watchers watcherSet
 }
 
 var _ Vault = &MemoryVault{}
+var _ WatchVault = &MemoryVault{}
+var _ EncodedVault = &MemoryVault{}
 
 // NewMemoryVault creates a new memory vault.
 func NewMemoryVault(opts ...MemoryVaultOption) *MemoryVault {
@@ -59,6 +63,45 @@ func (v *MemoryVault) String() string {
 // WriteFile reads data from the provided io.Reader and then writes it to the memory vault
 // at the provided path.
 func (v *MemoryVault) WriteFile(path string, r io.Reader, opts ...FileOption) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	f := newMemoryFile(path, make([]byte, len(data)), opts...)
+	copy(f.data, data)
+
+	return v.putFile(path, f)
+}
+
+// Link adds a file at the provided path that shares the contents, modified time and
+// encoded variants of the existing file at the target path, similar to a hard link.
+// Links are stored without duplicating the file's data when the vault is encoded
+// with MarshalBinary. If the target file is later replaced or removed, the link keeps
+// the previous contents and is encoded as a regular file.
+func (v *MemoryVault) Link(target string, path string) error {
+	targetFile, err := v.getFile(target)
+	if err != nil {
+		return err
+	}
+
+	if targetFile.encodings == nil {
+		targetFile.encodings = map[string][]byte{}
+	}
+
+	f := newMemoryFile(path, targetFile.data, FileModTime(targetFile.modTime))
+	f.encodings = targetFile.encodings
+	f.linkTarget = targetFile.FullPath()
+	if targetFile.linkTarget != "" {
+		f.linkTarget = targetFile.linkTarget
+	}
+
+	return v.putFile(path, f)
+}
+
+// putFile adds the file to the vault at the provided path, creating any parent
+// directories needed and replacing an existing file.
+func (v *MemoryVault) putFile(path string, f *memoryFile) error {
 	pathParts := strings.Split(path, string(os.PathSeparator))
 	curRoot := v.root
 	for idx := 0; idx < len(pathParts)-1; idx++ {
@@ -79,18 +122,151 @@ func (v *MemoryVault) WriteFile(path string, r io.Reader, opts ...FileOption) er
 		curRoot = dirRoot
 	}
 
+	fileName := pathParts[len(pathParts)-1]
+	evtType := WatchEventCreate
+	if _, ok := curRoot.nodes[fileName]; ok {
+		evtType = WatchEventModify
+	}
+	curRoot.nodes[fileName] = f
+
+	v.watchers.Send(WatchEvent{Type: evtType, Path: f.FullPath()})
+
+	return nil
+}
+
+// WriteFileEncoding reads data from the provided io.Reader and stores it as a variant
+// of the existing file at the provided path with the given content encoding, such as
+// a precompressed version of the file. Variants are removed when the file is written
+// again using WriteFile.
+func (v *MemoryVault) WriteFileEncoding(path string, encoding string, r io.Reader) error {
+	f, err := v.getFile(path)
+	if err != nil {
+		return err
+	}
+
 	data, err := ioutil.ReadAll(r)
 	if err != nil {
 		return err
 	}
 
-	f := newMemoryFile(path, make([]byte, len(data)), opts...)
-	copy(f.data, data)
-	curRoot.nodes[pathParts[len(pathParts)-1]] = f
+	if f.encodings == nil {
+		f.encodings = map[string][]byte{}
+	}
+	f.encodings[encoding] = data
 
 	return nil
 }
 
+// FileEncodings returns the encodings of the variants stored for the file at the
+// provided path, sorted by name.
+func (v *MemoryVault) FileEncodings(name string) ([]string, error) {
+	f, err := v.getFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []string
+	for encoding := range f.encodings {
+		res = append(res, encoding)
+	}
+	sort.Strings(res)
+
+	return res, nil
+}
+
+// ReadFileEncoding returns the contents of the variant of the file at the provided
+// path with the given encoding.
+func (v *MemoryVault) ReadFileEncoding(name string, encoding string) ([]byte, error) {
+	f, err := v.getFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := f.encodings[encoding]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+
+	res := make([]byte, len(data))
+	copy(res, data)
+
+	return res, nil
+}
+
+func (v *MemoryVault) getFile(name string) (*memoryFile, error) {
+	tokens, err := splitPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := v.root.GetNode(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	f, ok := node.(*memoryFile)
+	if !ok {
+		return nil, fmt.Errorf("not a file: %s", name)
+	}
+
+	return f, nil
+}
+
+// Remove removes the file or directory at the provided path from the memory vault.
+// If the path is a directory, everything inside the directory is removed as well.
+func (v *MemoryVault) Remove(name string) error {
+	tokens, err := splitPath(name)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 1 && tokens[0] == filesystemRootPath {
+		return fmt.Errorf("cannot remove the root directory")
+	}
+
+	parentNode, err := v.root.GetNode(tokens[:len(tokens)-1])
+	if err != nil {
+		return err
+	}
+	parentDir, ok := parentNode.(*memoryDir)
+	if !ok {
+		return fmt.Errorf("not a directory: %s", strings.Join(tokens[:len(tokens)-1], pathSeparator))
+	}
+
+	nodeName := tokens[len(tokens)-1]
+	node, ok := parentDir.nodes[nodeName]
+	if !ok {
+		return os.ErrNotExist
+	}
+	delete(parentDir.nodes, nodeName)
+
+	var removed []string
+	var collect func(fsNode)
+	collect = func(n fsNode) {
+		if dirNode, ok := n.(*memoryDir); ok {
+			for _, child := range dirNode.nodes {
+				collect(child)
+			}
+			return
+		}
+		removed = append(removed, n.FullPath())
+	}
+	collect(node)
+
+	sort.Strings(removed)
+	for _, removedPath := range removed {
+		v.watchers.Send(WatchEvent{Type: WatchEventRemove, Path: removedPath})
+	}
+
+	return nil
+}
+
+// Watch returns a Watcher for files in the memory vault matching the provided
+// pattern. Events are sent when files are written with WriteFile or removed
+// with Remove.
+func (v *MemoryVault) Watch(pattern string) (*Watcher, error) {
+	return v.watchers.Add(pattern)
+}
+
 // Open will open the file at the provided path from the in-memory vault.
 func (v *MemoryVault) Open(name string) (File, error) {
 	tokens, err := splitPath(name)
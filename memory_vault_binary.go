@@ -6,16 +6,41 @@ import (
 	"compress/gzip"
 	"io"
 	"os"
+	"reflect"
+	"sort"
+	"time"
 )
 
-// MarshalBinary encodes the MemoryVault into a binary representation.
+const (
+	// paxEncodingKey is the PAX record used to mark a tar entry as an encoded
+	// variant of the file with the same name.
+	paxEncodingKey = "GOBLIN.encoding"
+
+	// gzipUnknownOS is the gzip header value for an unknown operating system.
+	gzipUnknownOS = 255
+)
+
+// MarshalBinary encodes the MemoryVault into a binary representation. The
+// representation only depends on the vault's contents, so identical vaults are
+// always encoded to identical bytes.
 func (v *MemoryVault) MarshalBinary() ([]byte, error) {
+	return v.marshalBinaryLevel(gzip.DefaultCompression)
+}
+
+// marshalBinaryLevel encodes the MemoryVault into a binary representation compressed
+// with the provided gzip compression level.
+func (v *MemoryVault) marshalBinaryLevel(level int) ([]byte, error) {
 	buf := bytes.NewBuffer(nil)
-	gw := gzip.NewWriter(buf)
+	gw, err := gzip.NewWriterLevel(buf, level)
+	if err != nil {
+		return nil, err
+	}
+	// Set the gzip header explicitly so it never contains host-specific values.
+	gw.Header = gzip.Header{OS: gzipUnknownOS}
 	tw := tar.NewWriter(gw)
 
 	var paths []string
-	err := Walk(v, ".", func(path string, info os.FileInfo, err error) error {
+	err = Walk(v, ".", func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -29,6 +54,7 @@ func (v *MemoryVault) MarshalBinary() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	sort.Strings(paths)
 
 	for _, path := range paths {
 		tokens, err := splitPath(path)
@@ -45,11 +71,22 @@ func (v *MemoryVault) MarshalBinary() ([]byte, error) {
 			return nil, err
 		}
 
-		err = tw.WriteHeader(&tar.Header{
-			Name:    node.FullPath(),
-			ModTime: fInfo.ModTime(),
-			Size:    fInfo.Size(),
-		})
+		f, ok := node.(*memoryFile)
+		if ok && v.sharesLinkTarget(f) {
+			// Links share data with their target so only the link needs to be
+			// stored. Encoded variants are shared as well.
+			header := newTarHeader(node.FullPath(), fInfo.ModTime(), 0)
+			header.Typeflag = tar.TypeLink
+			header.Linkname = f.linkTarget
+			err = tw.WriteHeader(header)
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		err = tw.WriteHeader(newTarHeader(node.FullPath(), fInfo.ModTime(), fInfo.Size()))
 		if err != nil {
 			return nil, err
 		}
@@ -59,16 +96,37 @@ func (v *MemoryVault) MarshalBinary() ([]byte, error) {
 			return nil, err
 		}
 
-		totalWritten := 0
-		for {
-			n, err := tw.Write(data[totalWritten:])
+		err = writeTarData(tw, data)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			continue
+		}
+
+		var encodings []string
+		for encoding := range f.encodings {
+			encodings = append(encodings, encoding)
+		}
+		sort.Strings(encodings)
+
+		for _, encoding := range encodings {
+			encData := f.encodings[encoding]
+
+			header := newTarHeader(node.FullPath(), fInfo.ModTime(), int64(len(encData)))
+			header.Format = tar.FormatPAX
+			header.PAXRecords = map[string]string{
+				paxEncodingKey: encoding,
+			}
+			err = tw.WriteHeader(header)
 			if err != nil {
 				return nil, err
 			}
-			totalWritten = totalWritten + n
 
-			if totalWritten >= len(data) {
-				break
+			err = writeTarData(tw, encData)
+			if err != nil {
+				return nil, err
 			}
 		}
 	}
@@ -86,6 +144,47 @@ func (v *MemoryVault) MarshalBinary() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// sharesLinkTarget reports whether the file is a link whose target still exists and
+// has the same contents, modified time and encoded variants as the link. If the target
+// was replaced or removed after the link was created, the link is stored as a regular
+// file so it keeps its own contents.
+func (v *MemoryVault) sharesLinkTarget(f *memoryFile) bool {
+	if f.linkTarget == "" {
+		return false
+	}
+
+	target, err := v.getFile(f.linkTarget)
+	if err != nil {
+		return false
+	}
+
+	return sameBytes(f.data, target.data) &&
+		f.modTime.Equal(target.modTime) &&
+		reflect.ValueOf(f.encodings).Pointer() == reflect.ValueOf(target.encodings).Pointer()
+}
+
+// sameBytes reports whether both slices refer to the same data in memory.
+func sameBytes(a []byte, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	return len(a) == 0 || &a[0] == &b[0]
+}
+
+// newTarHeader creates a header for a regular file in a vault's binary representation.
+// Only the values goblin uses are set, so the header doesn't depend on the host the
+// vault was built on.
+func newTarHeader(name string, modTime time.Time, size int64) *tar.Header {
+	return &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     size,
+		ModTime:  modTime.UTC().Truncate(time.Second),
+	}
+}
+
 // UnmarshalBinary decodes the provided data into the MemoryVault.
 func (v *MemoryVault) UnmarshalBinary(data []byte) error {
 	r := bytes.NewReader(data)
@@ -96,6 +195,10 @@ func (v *MemoryVault) UnmarshalBinary(data []byte) error {
 	}
 	tr := tar.NewReader(gr)
 
+	// Links are created after all other files are loaded since their targets
+	// may appear later in the archive.
+	var links []*tar.Header
+
 	for {
 		header, err := tr.Next()
 		if err == io.EOF {
@@ -104,6 +207,11 @@ func (v *MemoryVault) UnmarshalBinary(data []byte) error {
 			return err
 		}
 
+		if header.Typeflag == tar.TypeLink {
+			links = append(links, header)
+			continue
+		}
+
 		b := bytes.NewBuffer(nil)
 		buf := make([]byte, 4096)
 		for {
@@ -133,14 +241,38 @@ func (v *MemoryVault) UnmarshalBinary(data []byte) error {
 			}
 		}
 
-		err = v.WriteFile(
-			header.Name,
-			b,
-			FileModTime(header.ModTime),
-		)
+		if encoding, ok := header.PAXRecords[paxEncodingKey]; ok {
+			err = v.WriteFileEncoding(header.Name, encoding, b)
+		} else {
+			err = v.WriteFile(
+				header.Name,
+				b,
+				FileModTime(header.ModTime),
+			)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, link := range links {
+		err = v.Link(link.Linkname, link.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeTarData(tw *tar.Writer, data []byte) error {
+	totalWritten := 0
+	for totalWritten < len(data) {
+		n, err := tw.Write(data[totalWritten:])
 		if err != nil {
 			return err
 		}
+		totalWritten = totalWritten + n
 	}
 
 	return nil
@@ -1,7 +1,11 @@
 package goblin
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
 	"testing"
 	"time"
 
@@ -49,4 +53,170 @@ func TestMemoryVaultBinaryMarshalling(t *testing.T) {
 		assert.Equal(t, time.Unix(2, 0), fInfo.ModTime())
 		assert.Equal(t, []byte{0x02}, data)
 	})
+
+	t.Run("encoded variants are preserved", func(t *testing.T) {
+		mv := NewMemoryVault()
+
+		err := mv.WriteFile(
+			"file.txt", bytes.NewReader([]byte{0x01}),
+			FileModTime(time.Unix(1, 0)),
+		)
+		require.NoError(t, err)
+		err = mv.WriteFileEncoding("file.txt", EncodingGzip, bytes.NewReader([]byte{0x02}))
+		require.NoError(t, err)
+
+		data, err := mv.MarshalBinary()
+		require.NoError(t, err)
+
+		mv = NewMemoryVault()
+		err = mv.UnmarshalBinary(data)
+		require.NoError(t, err)
+
+		data, err = mv.ReadFile("file.txt")
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x01}, data)
+
+		data, err = mv.ReadFileEncoding("file.txt", EncodingGzip)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x02}, data)
+	})
+	t.Run("links are preserved", func(t *testing.T) {
+		mv := NewMemoryVault()
+
+		err := mv.WriteFile(
+			"static/app.js", bytes.NewReader([]byte{0x01}),
+			FileModTime(time.Unix(1, 0)),
+		)
+		require.NoError(t, err)
+		err = mv.WriteFileEncoding("static/app.js", EncodingGzip, bytes.NewReader([]byte{0x02}))
+		require.NoError(t, err)
+		// The link sorts before its target to make sure order doesn't matter
+		err = mv.Link("static/app.js", "static/app.0123.js")
+		require.NoError(t, err)
+
+		data, err := mv.MarshalBinary()
+		require.NoError(t, err)
+
+		mv = NewMemoryVault()
+		err = mv.UnmarshalBinary(data)
+		require.NoError(t, err)
+
+		data, err = mv.ReadFile("static/app.0123.js")
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x01}, data)
+
+		fInfo, err := mv.Stat("static/app.0123.js")
+		require.NoError(t, err)
+		assert.Equal(t, time.Unix(1, 0), fInfo.ModTime())
+
+		data, err = mv.ReadFileEncoding("static/app.0123.js", EncodingGzip)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x02}, data)
+	})
+	t.Run("links keep contents of replaced targets", func(t *testing.T) {
+		mv := NewMemoryVault()
+
+		require.NoError(t, mv.WriteFile("a.js", bytes.NewBufferString("one"), FileModTime(time.Unix(1, 0))))
+		require.NoError(t, mv.WriteFileEncoding("a.js", EncodingGzip, bytes.NewBufferString("gzip one")))
+		require.NoError(t, mv.Link("a.js", "a.0123.js"))
+		require.NoError(t, mv.WriteFile("a.js", bytes.NewBufferString("two"), FileModTime(time.Unix(2, 0))))
+
+		data, err := mv.MarshalBinary()
+		require.NoError(t, err)
+
+		mv = NewMemoryVault()
+		require.NoError(t, mv.UnmarshalBinary(data))
+
+		data, err = mv.ReadFile("a.0123.js")
+		require.NoError(t, err)
+		assert.Equal(t, "one", string(data))
+
+		fInfo, err := mv.Stat("a.0123.js")
+		require.NoError(t, err)
+		assert.Equal(t, time.Unix(1, 0), fInfo.ModTime())
+
+		data, err = mv.ReadFileEncoding("a.0123.js", EncodingGzip)
+		require.NoError(t, err)
+		assert.Equal(t, "gzip one", string(data))
+
+		data, err = mv.ReadFile("a.js")
+		require.NoError(t, err)
+		assert.Equal(t, "two", string(data))
+
+		_, err = mv.ReadFileEncoding("a.js", EncodingGzip)
+		assert.Error(t, err)
+	})
+	t.Run("links keep contents of removed targets", func(t *testing.T) {
+		mv := NewMemoryVault()
+
+		require.NoError(t, mv.WriteFile("a.js", bytes.NewBufferString("one"), FileModTime(time.Unix(1, 0))))
+		require.NoError(t, mv.Link("a.js", "a.0123.js"))
+		require.NoError(t, mv.Remove("a.js"))
+
+		data, err := mv.MarshalBinary()
+		require.NoError(t, err)
+
+		mv = NewMemoryVault()
+		require.NoError(t, mv.UnmarshalBinary(data))
+
+		data, err = mv.ReadFile("a.0123.js")
+		require.NoError(t, err)
+		assert.Equal(t, "one", string(data))
+
+		_, err = mv.Stat("a.js")
+		assert.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestMemoryVaultMarshalBinaryReproducible(t *testing.T) {
+	modTime := time.Date(2020, 6, 1, 12, 0, 0, 500, time.Local)
+	newVault := func(order []string) *MemoryVault {
+		v := NewMemoryVault()
+		for _, name := range order {
+			require.NoError(t, v.WriteFile(name, bytes.NewBufferString("data for "+name), FileModTime(modTime)))
+		}
+		require.NoError(t, v.WriteFileEncoding("b/index.html", EncodingGzip, bytes.NewBufferString("gzip")))
+		require.NoError(t, v.WriteFileEncoding("b/index.html", EncodingDeflate, bytes.NewBufferString("deflate")))
+		require.NoError(t, v.Link("a.txt", "a.1234.txt"))
+		return v
+	}
+
+	data1, err := newVault([]string{"a.txt", "b/index.html", "c/d/e.txt"}).MarshalBinary()
+	require.NoError(t, err)
+	data2, err := newVault([]string{"c/d/e.txt", "b/index.html", "a.txt"}).MarshalBinary()
+	require.NoError(t, err)
+	assert.Equal(t, data1, data2)
+
+	gr, err := gzip.NewReader(bytes.NewReader(data1))
+	require.NoError(t, err)
+	assert.Equal(t, "", gr.Header.Name)
+	assert.True(t, gr.Header.ModTime.IsZero())
+	assert.Equal(t, byte(gzipUnknownOS), gr.Header.OS)
+
+	tr := tar.NewReader(gr)
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		names = append(names, header.Name)
+		assert.Equal(t, 0, header.Uid)
+		assert.Equal(t, 0, header.Gid)
+		assert.Equal(t, "", header.Uname)
+		assert.Equal(t, "", header.Gname)
+		assert.Equal(t, int64(0644), header.Mode)
+		assert.Equal(t, modTime.Truncate(time.Second).Unix(), header.ModTime.Unix())
+		assert.Equal(t, 0, header.ModTime.Nanosecond())
+	}
+	assert.Equal(t, []string{
+		"a.1234.txt",
+		"a.txt",
+		"b/index.html",
+		"b/index.html",
+		"b/index.html",
+		"c/d/e.txt",
+	}, names)
 }
@@ -2,6 +2,7 @@ package goblin
 
 import (
 	"bytes"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -96,3 +97,157 @@ func TestMemoryVaultGlob(t *testing.T) {
 		assert.Nil(t, names)
 	})
 }
+
+func TestMemoryVaultRemove(t *testing.T) {
+	t.Run("remove file", func(t *testing.T) {
+		v := newTestVault()
+		require.NoError(t, v.Remove("dir1/file.txt"))
+
+		_, err := v.Stat("dir1/file.txt")
+		assert.Equal(t, os.ErrNotExist, err)
+
+		_, err = v.Stat("dir1/dir11/file.txt")
+		assert.NoError(t, err)
+	})
+
+	t.Run("remove directory", func(t *testing.T) {
+		v := newTestVault()
+		require.NoError(t, v.Remove("dir2"))
+
+		_, err := v.Stat("dir2")
+		assert.Equal(t, os.ErrNotExist, err)
+	})
+
+	t.Run("remove missing file", func(t *testing.T) {
+		v := newTestVault()
+		assert.Equal(t, os.ErrNotExist, v.Remove("dir1/missing.txt"))
+	})
+
+	t.Run("remove root", func(t *testing.T) {
+		v := newTestVault()
+		assert.Error(t, v.Remove("."))
+	})
+}
+
+func TestMemoryVaultWatch(t *testing.T) {
+	t.Run("write and remove events", func(t *testing.T) {
+		v := newTestVault()
+		w, err := v.Watch(".")
+		require.NoError(t, err)
+		defer w.Close()
+
+		require.NoError(t, v.WriteFile("new.txt", bytes.NewBuffer([]byte{0x01})))
+		require.NoError(t, v.WriteFile("file.txt", bytes.NewBuffer([]byte{0x02})))
+		require.NoError(t, v.Remove("dir1"))
+
+		assert.Equal(t, WatchEvent{Type: WatchEventCreate, Path: "new.txt"}, receiveWatchEvent(t, w))
+		assert.Equal(t, WatchEvent{Type: WatchEventModify, Path: "file.txt"}, receiveWatchEvent(t, w))
+		assert.Equal(t, WatchEvent{Type: WatchEventRemove, Path: "dir1/dir11/file.txt"}, receiveWatchEvent(t, w))
+		assert.Equal(t, WatchEvent{Type: WatchEventRemove, Path: "dir1/file.txt"}, receiveWatchEvent(t, w))
+	})
+
+	t.Run("only matching events", func(t *testing.T) {
+		v := newTestVault()
+		w, err := v.Watch("dir2/*/*.txt")
+		require.NoError(t, err)
+		defer w.Close()
+
+		require.NoError(t, v.WriteFile("dir1/file.txt", bytes.NewBuffer([]byte{0x01})))
+		require.NoError(t, v.WriteFile("dir2/dir21/file.txt", bytes.NewBuffer([]byte{0x01})))
+
+		assert.Equal(t, WatchEvent{Type: WatchEventModify, Path: "dir2/dir21/file.txt"}, receiveWatchEvent(t, w))
+	})
+}
+
+func TestMemoryVaultEncodings(t *testing.T) {
+	t.Run("write and read variants", func(t *testing.T) {
+		v := newTestVault()
+		require.NoError(t, v.WriteFileEncoding("dir1/file.txt", EncodingGzip, bytes.NewBuffer([]byte{0x10})))
+		require.NoError(t, v.WriteFileEncoding("dir1/file.txt", EncodingDeflate, bytes.NewBuffer([]byte{0x11})))
+
+		encodings, err := v.FileEncodings("dir1/file.txt")
+		require.NoError(t, err)
+		assert.Equal(t, []string{EncodingDeflate, EncodingGzip}, encodings)
+
+		data, err := v.ReadFileEncoding("dir1/file.txt", EncodingGzip)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x10}, data)
+
+		_, err = v.ReadFileEncoding("dir1/file.txt", "br")
+		assert.Equal(t, os.ErrNotExist, err)
+
+		data, err = v.ReadFile("dir1/file.txt")
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x02}, data)
+	})
+
+	t.Run("variants are not listed", func(t *testing.T) {
+		v := newTestVault()
+		require.NoError(t, v.WriteFileEncoding("dir1/file.txt", EncodingGzip, bytes.NewBuffer([]byte{0x10})))
+
+		fi, err := v.ReadDir("dir1")
+		require.NoError(t, err)
+		assert.Len(t, fi, 2)
+
+		names, err := v.Glob("dir1/*")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"dir1/dir11", "dir1/file.txt"}, names)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		v := newTestVault()
+		err := v.WriteFileEncoding("missing.txt", EncodingGzip, bytes.NewBuffer([]byte{0x10}))
+		assert.Equal(t, os.ErrNotExist, err)
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		v := newTestVault()
+		err := v.WriteFileEncoding("dir1", EncodingGzip, bytes.NewBuffer([]byte{0x10}))
+		assert.EqualError(t, err, "not a file: dir1")
+	})
+
+	t.Run("rewriting a file removes variants", func(t *testing.T) {
+		v := newTestVault()
+		require.NoError(t, v.WriteFileEncoding("file.txt", EncodingGzip, bytes.NewBuffer([]byte{0x10})))
+		require.NoError(t, v.WriteFile("file.txt", bytes.NewBuffer([]byte{0x01})))
+
+		encodings, err := v.FileEncodings("file.txt")
+		require.NoError(t, err)
+		assert.Empty(t, encodings)
+	})
+}
+
+func TestMemoryVaultLink(t *testing.T) {
+	t.Run("link shares contents", func(t *testing.T) {
+		v := newTestVault()
+		require.NoError(t, v.WriteFileEncoding("dir1/file.txt", EncodingGzip, bytes.NewBuffer([]byte{0x10})))
+		require.NoError(t, v.Link("dir1/file.txt", "links/file.txt"))
+
+		data, err := v.ReadFile("links/file.txt")
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x02}, data)
+
+		data, err = v.ReadFileEncoding("links/file.txt", EncodingGzip)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x10}, data)
+
+		fInfo, err := v.Stat("links/file.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "file.txt", fInfo.Name())
+	})
+
+	t.Run("link keeps contents when target is replaced", func(t *testing.T) {
+		v := newTestVault()
+		require.NoError(t, v.Link("file.txt", "link.txt"))
+		require.NoError(t, v.WriteFile("file.txt", bytes.NewBuffer([]byte{0x09})))
+
+		data, err := v.ReadFile("link.txt")
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x01}, data)
+	})
+
+	t.Run("missing target", func(t *testing.T) {
+		v := newTestVault()
+		assert.Equal(t, os.ErrNotExist, v.Link("missing.txt", "link.txt"))
+	})
+}
@@ -2,6 +2,7 @@ package goblin
 
 import (
 	"fmt"
+	"path"
 	"strings"
 )
 
@@ -49,3 +50,15 @@ func validatePath(path []string) error {
 
 	return nil
 }
+
+// matchPathGlob reports whether the vault-relative path matches the glob pattern.
+// Patterns without a path separator are matched against the file's base name so
+// "*.js" matches JavaScript files in any directory, while patterns with a path
+// separator are matched against the full path.
+func matchPathGlob(pattern string, name string) (bool, error) {
+	if !strings.Contains(pattern, pathSeparator) {
+		return path.Match(pattern, path.Base(name))
+	}
+
+	return path.Match(pattern, name)
+}
@@ -100,3 +100,38 @@ func TestValidatePath(t *testing.T) {
 		assert.EqualError(t, err, "path cannot be empty")
 	})
 }
+
+func TestMatchPathGlob(t *testing.T) {
+	t.Run("base name pattern", func(t *testing.T) {
+		match, err := matchPathGlob("*.js", "app.js")
+		require.NoError(t, err)
+		assert.True(t, match)
+
+		match, err = matchPathGlob("*.js", "static/js/app.js")
+		require.NoError(t, err)
+		assert.True(t, match)
+
+		match, err = matchPathGlob("*.js", "static/app.css")
+		require.NoError(t, err)
+		assert.False(t, match)
+	})
+
+	t.Run("full path pattern", func(t *testing.T) {
+		match, err := matchPathGlob("static/*.js", "static/app.js")
+		require.NoError(t, err)
+		assert.True(t, match)
+
+		match, err = matchPathGlob("static/*.js", "app.js")
+		require.NoError(t, err)
+		assert.False(t, match)
+
+		match, err = matchPathGlob("static/*.js", "static/js/app.js")
+		require.NoError(t, err)
+		assert.False(t, match)
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		_, err := matchPathGlob("[", "app.js")
+		assert.Error(t, err)
+	})
+}
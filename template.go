@@ -0,0 +1,361 @@
+package goblin
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path"
+	"sync"
+	texttemplate "text/template"
+)
+
+// walkTemplates calls parse for every file in the vault with a path matching the
+// pattern, in path order. Patterns without a path separator are matched against
+// the file's name.
+func walkTemplates(v Vault, pattern string, parse func(name string, data []byte) error) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+
+	var names []string
+	err := Walk(v, filesystemRootPath, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		match, err := matchPathGlob(pattern, name)
+		if err != nil {
+			return err
+		}
+		if match {
+			names = append(names, name)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("pattern matches no files: %#q", pattern)
+	}
+
+	for _, name := range names {
+		data, err := v.ReadFile(name)
+		if err != nil {
+			return err
+		}
+
+		err = parse(name, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ParseHTMLTemplates parses every file in the vault with a path matching the pattern
+// as an html/template and associates it with t. Templates are named using their
+// vault-relative path, such as "layouts/base.html". Patterns without a path separator
+// are matched against the file's name, so "*.html" matches files in any directory.
+//
+// If t is nil a new template is created and named after the first matching file.
+// Provide a template with functions already added to use custom functions.
+func ParseHTMLTemplates(t *htmltemplate.Template, v Vault, pattern string) (*htmltemplate.Template, error) {
+	err := walkTemplates(v, pattern, func(name string, data []byte) error {
+		var tmpl *htmltemplate.Template
+		switch {
+		case t == nil:
+			t = htmltemplate.New(name)
+			tmpl = t
+		case name == t.Name():
+			tmpl = t
+		default:
+			tmpl = t.New(name)
+		}
+
+		_, err := tmpl.Parse(string(data))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// ParseTextTemplates parses every file in the vault with a path matching the pattern
+// as a text/template and associates it with t. Templates are named using their
+// vault-relative path. See ParseHTMLTemplates for how patterns are matched.
+func ParseTextTemplates(t *texttemplate.Template, v Vault, pattern string) (*texttemplate.Template, error) {
+	err := walkTemplates(v, pattern, func(name string, data []byte) error {
+		var tmpl *texttemplate.Template
+		switch {
+		case t == nil:
+			t = texttemplate.New(name)
+			tmpl = t
+		case name == t.Name():
+			tmpl = t
+		default:
+			tmpl = t.New(name)
+		}
+
+		_, err := tmpl.Parse(string(data))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// TemplateLoaderOption is an option used when creating a template loader.
+type TemplateLoaderOption func(*templateLoaderOptions)
+
+type templateLoaderOptions struct {
+	HotReload bool
+}
+
+// TemplateHotReload causes a template loader to re-parse its templates when files
+// matching its pattern change. Hot reloading only happens if the vault implements
+// WatchVault and the vault, or the vault currently selected by a VaultSelector, is
+// a FilesystemVault. This allows templates to be reloaded in development when a
+// FilesystemVault is selected without affecting an embedded vault in production.
+func TemplateHotReload(enabled bool) TemplateLoaderOption {
+	return func(opts *templateLoaderOptions) {
+		opts.HotReload = enabled
+	}
+}
+
+// templateLoader handles the parsing and reloading shared by the html/template and
+// text/template loaders.
+type templateLoader struct {
+	v       Vault
+	pattern string
+	parse   func() error
+
+	lock    sync.Mutex
+	stale   bool
+	watcher *Watcher
+}
+
+func newTemplateLoader(
+	v Vault, pattern string, parse func() error, opts ...TemplateLoaderOption,
+) (*templateLoader, error) {
+	loaderOpts := &templateLoaderOptions{}
+	for _, opt := range opts {
+		opt(loaderOpts)
+	}
+
+	tl := &templateLoader{
+		v:       v,
+		pattern: pattern,
+		parse:   parse,
+	}
+
+	err := tl.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	if loaderOpts.HotReload && isFilesystemVault(v) {
+		wv := v.(WatchVault)
+		w, err := wv.Watch(filesystemRootPath)
+		if err != nil {
+			return nil, err
+		}
+		tl.watcher = w
+
+		go tl.watch(w)
+	}
+
+	return tl, nil
+}
+
+func (tl *templateLoader) watch(w *Watcher) {
+	for evt := range w.Events() {
+		match, err := matchPathGlob(tl.pattern, evt.Path)
+		if err != nil || !match {
+			continue
+		}
+
+		tl.lock.Lock()
+		tl.stale = true
+		tl.lock.Unlock()
+	}
+}
+
+// reload re-parses the templates if any have changed since they were last parsed.
+func (tl *templateLoader) reload() error {
+	tl.lock.Lock()
+	defer tl.lock.Unlock()
+
+	if !tl.stale {
+		return nil
+	}
+
+	err := tl.parse()
+	if err != nil {
+		return err
+	}
+	tl.stale = false
+
+	return nil
+}
+
+func (tl *templateLoader) close() error {
+	if tl.watcher == nil {
+		return nil
+	}
+
+	return tl.watcher.Close()
+}
+
+// isFilesystemVault reports whether the vault, or the vault currently selected by
+// a vault selector, is a FilesystemVault that can be watched for changes.
+func isFilesystemVault(v Vault) bool {
+	if vs, ok := v.(*VaultSelector); ok {
+		selected, err := vs.GetVault()
+		if err != nil {
+			return false
+		}
+		v = selected
+	}
+
+	_, ok := v.(*FilesystemVault)
+	return ok
+}
+
+// HTMLTemplateLoader parses html/template templates from a vault and optionally
+// re-parses them when they change.
+type HTMLTemplateLoader struct {
+	*templateLoader
+
+	base    func() *htmltemplate.Template
+	tmplMtx sync.RWMutex
+	tmpl    *htmltemplate.Template
+}
+
+// NewHTMLTemplateLoader creates a loader that parses all templates in the vault
+// matching the pattern using ParseHTMLTemplates. If base is not nil it's called
+// before each parse to create the template the vault's templates are added to,
+// which allows functions to be added with Funcs.
+func NewHTMLTemplateLoader(
+	v Vault, pattern string, base func() *htmltemplate.Template, opts ...TemplateLoaderOption,
+) (*HTMLTemplateLoader, error) {
+	l := &HTMLTemplateLoader{
+		base: base,
+	}
+
+	tl, err := newTemplateLoader(v, pattern, func() error {
+		var t *htmltemplate.Template
+		if l.base != nil {
+			t = l.base()
+		}
+
+		t, err := ParseHTMLTemplates(t, v, pattern)
+		if err != nil {
+			return err
+		}
+
+		l.tmplMtx.Lock()
+		l.tmpl = t
+		l.tmplMtx.Unlock()
+
+		return nil
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	l.templateLoader = tl
+
+	return l, nil
+}
+
+// Template returns the parsed templates, re-parsing them first if hot reloading
+// is enabled and any of them have changed.
+func (l *HTMLTemplateLoader) Template() (*htmltemplate.Template, error) {
+	err := l.reload()
+	if err != nil {
+		return nil, err
+	}
+
+	l.tmplMtx.RLock()
+	defer l.tmplMtx.RUnlock()
+
+	return l.tmpl, nil
+}
+
+// Close stops watching the vault for changes.
+func (l *HTMLTemplateLoader) Close() error {
+	return l.close()
+}
+
+// TextTemplateLoader parses text/template templates from a vault and optionally
+// re-parses them when they change.
+type TextTemplateLoader struct {
+	*templateLoader
+
+	base    func() *texttemplate.Template
+	tmplMtx sync.RWMutex
+	tmpl    *texttemplate.Template
+}
+
+// NewTextTemplateLoader creates a loader that parses all templates in the vault
+// matching the pattern using ParseTextTemplates. See NewHTMLTemplateLoader for
+// details about base.
+func NewTextTemplateLoader(
+	v Vault, pattern string, base func() *texttemplate.Template, opts ...TemplateLoaderOption,
+) (*TextTemplateLoader, error) {
+	l := &TextTemplateLoader{
+		base: base,
+	}
+
+	tl, err := newTemplateLoader(v, pattern, func() error {
+		var t *texttemplate.Template
+		if l.base != nil {
+			t = l.base()
+		}
+
+		t, err := ParseTextTemplates(t, v, pattern)
+		if err != nil {
+			return err
+		}
+
+		l.tmplMtx.Lock()
+		l.tmpl = t
+		l.tmplMtx.Unlock()
+
+		return nil
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	l.templateLoader = tl
+
+	return l, nil
+}
+
+// Template returns the parsed templates, re-parsing them first if hot reloading
+// is enabled and any of them have changed.
+func (l *TextTemplateLoader) Template() (*texttemplate.Template, error) {
+	err := l.reload()
+	if err != nil {
+		return nil, err
+	}
+
+	l.tmplMtx.RLock()
+	defer l.tmplMtx.RUnlock()
+
+	return l.tmpl, nil
+}
+
+// Close stops watching the vault for changes.
+func (l *TextTemplateLoader) Close() error {
+	return l.close()
+}
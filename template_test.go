@@ -0,0 +1,169 @@
+package goblin
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTemplateVault() *MemoryVault {
+	v := NewMemoryVault()
+	_ = v.WriteFile("templates/layout.html", bytes.NewBufferString(
+		`<body>{{ template "templates/pages/index.html" . }}</body>`,
+	))
+	_ = v.WriteFile("templates/pages/index.html", bytes.NewBufferString(
+		`<p>{{ .Name }}</p>`,
+	))
+	_ = v.WriteFile("templates/email.txt", bytes.NewBufferString(
+		`Hello {{ .Name }}`,
+	))
+	return v
+}
+
+func TestParseHTMLTemplates(t *testing.T) {
+	t.Run("parse by name", func(t *testing.T) {
+		tmpl, err := ParseHTMLTemplates(nil, newTestTemplateVault(), "*.html")
+		require.NoError(t, err)
+		assert.Equal(t, "templates/layout.html", tmpl.Name())
+
+		buf := bytes.NewBuffer(nil)
+		err = tmpl.ExecuteTemplate(buf, "templates/layout.html", map[string]string{"Name": "<goblin>"})
+		require.NoError(t, err)
+		assert.Equal(t, "<body><p>&lt;goblin&gt;</p></body>", buf.String())
+
+		assert.Nil(t, tmpl.Lookup("templates/email.txt"))
+	})
+
+	t.Run("parse by full path", func(t *testing.T) {
+		tmpl, err := ParseHTMLTemplates(nil, newTestTemplateVault(), "templates/pages/*.html")
+		require.NoError(t, err)
+		assert.NotNil(t, tmpl.Lookup("templates/pages/index.html"))
+		assert.Nil(t, tmpl.Lookup("templates/layout.html"))
+	})
+
+	t.Run("existing template with funcs", func(t *testing.T) {
+		v := NewMemoryVault()
+		_ = v.WriteFile("page.html", bytes.NewBufferString(`{{ upper "goblin" }}`))
+
+		base := htmltemplate.New("base").Funcs(htmltemplate.FuncMap{"upper": strings.ToUpper})
+		tmpl, err := ParseHTMLTemplates(base, v, "*.html")
+		require.NoError(t, err)
+		assert.Same(t, base, tmpl)
+
+		buf := bytes.NewBuffer(nil)
+		require.NoError(t, tmpl.ExecuteTemplate(buf, "page.html", nil))
+		assert.Equal(t, "GOBLIN", buf.String())
+	})
+
+	t.Run("no matches", func(t *testing.T) {
+		_, err := ParseHTMLTemplates(nil, newTestTemplateVault(), "*.tmpl")
+		assert.EqualError(t, err, "pattern matches no files: `*.tmpl`")
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		v := NewMemoryVault()
+		_ = v.WriteFile("bad.html", bytes.NewBufferString(`{{ .Name `))
+
+		_, err := ParseHTMLTemplates(nil, v, "*.html")
+		assert.Error(t, err)
+	})
+}
+
+func TestParseTextTemplates(t *testing.T) {
+	tmpl, err := ParseTextTemplates(nil, newTestTemplateVault(), "*.txt")
+	require.NoError(t, err)
+
+	buf := bytes.NewBuffer(nil)
+	err = tmpl.ExecuteTemplate(buf, "templates/email.txt", map[string]string{"Name": "<goblin>"})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello <goblin>", buf.String())
+
+	base := texttemplate.New("base")
+	tmpl, err = ParseTextTemplates(base, newTestTemplateVault(), "*.txt")
+	require.NoError(t, err)
+	assert.Same(t, base, tmpl)
+}
+
+func TestHTMLTemplateLoader(t *testing.T) {
+	t.Run("memory vault is not reloaded", func(t *testing.T) {
+		v := newTestTemplateVault()
+		l, err := NewHTMLTemplateLoader(v, "*.html", nil, TemplateHotReload(true))
+		require.NoError(t, err)
+		defer l.Close()
+
+		assert.Nil(t, l.watcher)
+
+		tmpl, err := l.Template()
+		require.NoError(t, err)
+		assert.NotNil(t, tmpl.Lookup("templates/layout.html"))
+	})
+
+	t.Run("filesystem vault is reloaded", func(t *testing.T) {
+		td, err := ioutil.TempDir("", testTempPattern)
+		require.NoError(t, err)
+		defer os.RemoveAll(td)
+
+		require.NoError(t, ioutil.WriteFile(path.Join(td, "page.html"), []byte(`v1`), 0644))
+
+		fsVault := NewFilesystemVault(td, FilesystemVaultPollInterval(10*time.Millisecond))
+		vs := NewVaultSelector(SelectDefault(fsVault))
+
+		l, err := NewHTMLTemplateLoader(vs, "*.html", func() *htmltemplate.Template {
+			return htmltemplate.New("base")
+		}, TemplateHotReload(true))
+		require.NoError(t, err)
+		defer l.Close()
+
+		render := func() string {
+			tmpl, err := l.Template()
+			require.NoError(t, err)
+
+			buf := bytes.NewBuffer(nil)
+			require.NoError(t, tmpl.ExecuteTemplate(buf, "page.html", nil))
+			return buf.String()
+		}
+		assert.Equal(t, "v1", render())
+
+		require.NoError(t, ioutil.WriteFile(path.Join(td, "page.html"), []byte(`v2.0`), 0644))
+
+		assert.Eventually(t, func() bool {
+			return render() == "v2.0"
+		}, testWatchTimeout, 10*time.Millisecond)
+	})
+
+	t.Run("reload disabled", func(t *testing.T) {
+		td, err := ioutil.TempDir("", testTempPattern)
+		require.NoError(t, err)
+		defer os.RemoveAll(td)
+
+		require.NoError(t, ioutil.WriteFile(path.Join(td, "page.html"), []byte(`v1`), 0644))
+
+		l, err := NewHTMLTemplateLoader(NewFilesystemVault(td), "*.html", nil)
+		require.NoError(t, err)
+		defer l.Close()
+
+		assert.Nil(t, l.watcher)
+	})
+}
+
+func TestTextTemplateLoader(t *testing.T) {
+	l, err := NewTextTemplateLoader(newTestTemplateVault(), "*.txt", nil)
+	require.NoError(t, err)
+	defer l.Close()
+
+	tmpl, err := l.Template()
+	require.NoError(t, err)
+
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, tmpl.ExecuteTemplate(buf, "templates/email.txt", map[string]string{"Name": "goblin"}))
+	assert.Equal(t, "Hello goblin", buf.String())
+}
@@ -0,0 +1,159 @@
+package goblin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	// TransformCompactJSON is the name of the built-in transform returned by
+	// CompactJSONTransformer.
+	TransformCompactJSON = "compact-json"
+	// TransformLF is the name of the built-in transform returned by
+	// LFTransformer.
+	TransformLF = "lf"
+	// TransformTrimTrailingSpace is the name of the built-in transform returned by
+	// TrimTrailingSpaceTransformer.
+	TransformTrimTrailingSpace = "trim-trailing-space"
+)
+
+// Transformer changes the contents of a file before it's written to a vault, such as
+// minifying it or normalizing its line endings.
+type Transformer interface {
+	// Transform returns the new contents of the file with the provided vault path.
+	Transform(filePath string, data []byte) ([]byte, error)
+}
+
+// TransformerFunc is a function that implements Transformer.
+type TransformerFunc func(filePath string, data []byte) ([]byte, error)
+
+// Transform calls f with the file path and data.
+func (f TransformerFunc) Transform(filePath string, data []byte) ([]byte, error) {
+	return f(filePath, data)
+}
+
+type globTransformer struct {
+	glob        string
+	transformer Transformer
+}
+
+// MemoryBuilderTransform will cause files with a vault path matching the glob to be
+// passed through the provided transformers, in order, before they're written to the
+// vault. Globs without a path separator match the file's base name, so "*.json"
+// matches JSON files in any directory. Transforms from multiple options are applied
+// in the order the options are provided, and any precompressed variants and
+// fingerprints are created from the transformed contents.
+func MemoryBuilderTransform(glob string, transformers ...Transformer) MemoryBuilderOption {
+	return func(b *MemoryBuilder) {
+		for _, transformer := range transformers {
+			b.transforms = append(b.transforms, globTransformer{
+				glob:        glob,
+				transformer: transformer,
+			})
+		}
+	}
+}
+
+// BuiltinTransformer returns the built-in transformer with the provided name, one of
+// TransformCompactJSON, TransformLF or TransformTrimTrailingSpace.
+func BuiltinTransformer(name string) (Transformer, error) {
+	switch name {
+	case TransformCompactJSON:
+		return CompactJSONTransformer(), nil
+	case TransformLF:
+		return LFTransformer(), nil
+	case TransformTrimTrailingSpace:
+		return TrimTrailingSpaceTransformer(), nil
+	default:
+		return nil, fmt.Errorf("unknown transform %s", name)
+	}
+}
+
+// CompactJSONTransformer returns a transformer that removes insignificant whitespace
+// from JSON files. An error is returned if a file isn't valid JSON.
+func CompactJSONTransformer() Transformer {
+	return TransformerFunc(func(filePath string, data []byte) ([]byte, error) {
+		buf := bytes.NewBuffer(make([]byte, 0, len(data)))
+		err := json.Compact(buf, data)
+		if err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	})
+}
+
+// LFTransformer returns a transformer that converts CRLF line endings to LF.
+func LFTransformer() Transformer {
+	return TransformerFunc(func(filePath string, data []byte) ([]byte, error) {
+		return bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n")), nil
+	})
+}
+
+// TrimTrailingSpaceTransformer returns a transformer that removes spaces and tabs from
+// the end of each line. Line endings are left as they are.
+func TrimTrailingSpaceTransformer() Transformer {
+	return TransformerFunc(func(filePath string, data []byte) ([]byte, error) {
+		lines := bytes.SplitAfter(data, []byte("\n"))
+		for idx, line := range lines {
+			content := bytes.TrimRight(line, "\r\n")
+			ending := line[len(content):]
+			lines[idx] = append(bytes.TrimRight(content, " \t"), ending...)
+		}
+
+		return bytes.Join(lines, nil), nil
+	})
+}
+
+// ExecTransformer returns a transformer that runs an external command, such as a
+// minifier, for each file. The file's contents are written to the command's standard
+// input and its standard output is used as the new contents. The file's vault path
+// is available to the command in the GOBLIN_PATH environment variable. If the command
+// fails, the error includes anything it wrote to standard error.
+func ExecTransformer(name string, args ...string) Transformer {
+	return TransformerFunc(func(filePath string, data []byte) ([]byte, error) {
+		stdout := bytes.NewBuffer(nil)
+		stderr := bytes.NewBuffer(nil)
+
+		cmd := exec.Command(name, args...)
+		cmd.Env = append(os.Environ(), "GOBLIN_PATH="+filePath)
+		cmd.Stdin = bytes.NewReader(data)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+
+		err := cmd.Run()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return nil, fmt.Errorf("%s: %w: %s", name, err, msg)
+			}
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		return stdout.Bytes(), nil
+	})
+}
+
+// transform passes the file's data through the transformers with a glob matching its
+// vault path.
+func (b *MemoryBuilder) transform(filePath string, data []byte) ([]byte, error) {
+	for _, t := range b.transforms {
+		match, err := matchPathGlob(t.glob, filePath)
+		if err != nil {
+			return nil, err
+		}
+		if !match {
+			continue
+		}
+
+		data, err = t.transformer.Transform(filePath, data)
+		if err != nil {
+			return nil, fmt.Errorf("error transforming %s: %w", filePath, err)
+		}
+	}
+
+	return data, nil
+}
@@ -0,0 +1,116 @@
+package goblin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinTransformers(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: TransformCompactJSON, input: "{\n  \"a\": [1, 2],\n  \"b\": \"c d\"\n}\n", output: `{"a":[1,2],"b":"c d"}`},
+		{name: TransformLF, input: "a\r\nb\rc\r\n", output: "a\nb\rc\n"},
+		{name: TransformTrimTrailingSpace, input: "a  \nb\t\r\n  c \n\nd ", output: "a\nb\r\n  c\n\nd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transformer, err := BuiltinTransformer(tt.name)
+			require.NoError(t, err)
+
+			data, err := transformer.Transform("file", []byte(tt.input))
+			require.NoError(t, err)
+			assert.Equal(t, tt.output, string(data))
+		})
+	}
+
+	t.Run("invalid json", func(t *testing.T) {
+		_, err := CompactJSONTransformer().Transform("data.json", []byte("{"))
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown transform", func(t *testing.T) {
+		_, err := BuiltinTransformer("minify")
+		assert.EqualError(t, err, "unknown transform minify")
+	})
+}
+
+func TestExecTransformer(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	t.Run("transform with command", func(t *testing.T) {
+		transformer := ExecTransformer("sh", "-c", `tr a-z A-Z; printf ' %s' "$GOBLIN_PATH"`)
+		data, err := transformer.Transform("static/app.js", []byte("app"))
+		require.NoError(t, err)
+		assert.Equal(t, "APP static/app.js", string(data))
+	})
+
+	t.Run("failing command", func(t *testing.T) {
+		transformer := ExecTransformer("sh", "-c", "echo 'bad input' >&2; exit 3")
+		_, err := transformer.Transform("static/app.js", []byte("app"))
+		assert.EqualError(t, err, "sh: exit status 3: bad input")
+	})
+}
+
+func TestMemoryBuilderTransform(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	writeTestFiles(t, td, map[string]string{
+		"data/config.json": "{\r\n  \"debug\": true\r\n}\r\n",
+		"schema.sql":       "-- users\r\nCREATE TABLE users;  \r\n",
+		"index.html":       "<html>  \r\n",
+	})
+
+	stripSQLComments := TransformerFunc(func(filePath string, data []byte) ([]byte, error) {
+		var lines []string
+		for _, line := range strings.Split(string(data), "\n") {
+			if !strings.HasPrefix(line, "--") {
+				lines = append(lines, line)
+			}
+		}
+		return []byte(strings.Join(lines, "\n")), nil
+	})
+
+	b := NewMemoryBuilder(
+		MemoryBuilderTransform("*.json", CompactJSONTransformer()),
+		MemoryBuilderTransform("*.sql", LFTransformer(), stripSQLComments),
+		MemoryBuilderTransform("*.sql", TrimTrailingSpaceTransformer()),
+		MemoryBuilderPrecompress(EncodingGzip),
+	)
+	require.NoError(t, b.Include(td, []string{"*"}))
+
+	files := map[string]string{
+		"data/config.json": `{"debug":true}`,
+		"schema.sql":       "CREATE TABLE users;\n",
+		"index.html":       "<html>  \r\n",
+	}
+	for name, expected := range files {
+		data, err := b.v.ReadFile(name)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(data), name)
+	}
+
+	t.Run("transform errors", func(t *testing.T) {
+		b := NewMemoryBuilder(MemoryBuilderTransform("*.html",
+			TransformerFunc(func(filePath string, data []byte) ([]byte, error) {
+				return nil, fmt.Errorf("broken")
+			}),
+		))
+		err := b.Include(td, []string{"index.html"})
+		assert.EqualError(t, err, "error transforming index.html: broken")
+	})
+}
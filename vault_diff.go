@@ -0,0 +1,152 @@
+package goblin
+
+import (
+	"bytes"
+	"os"
+	"sort"
+	"strings"
+)
+
+// VaultChangeType is the type of difference a VaultChange describes.
+type VaultChangeType int
+
+const (
+	// VaultChangeAdded is a file that only exists in the new vault.
+	VaultChangeAdded VaultChangeType = iota + 1
+	// VaultChangeRemoved is a file that only exists in the old vault.
+	VaultChangeRemoved
+	// VaultChangeModified is a file with different contents or encoded variants
+	// in each vault.
+	VaultChangeModified
+)
+
+func (t VaultChangeType) String() string {
+	switch t {
+	case VaultChangeAdded:
+		return "added"
+	case VaultChangeRemoved:
+		return "removed"
+	case VaultChangeModified:
+		return "changed"
+	default:
+		return "unknown"
+	}
+}
+
+// VaultChange is a difference between the files in two vaults.
+type VaultChange struct {
+	Type VaultChangeType
+	Path string
+}
+
+func (c VaultChange) String() string {
+	return c.Type.String() + ": " + c.Path
+}
+
+// DiffVaults compares the files in two vaults and returns the differences, sorted by
+// path. Files are compared by their contents and, if both vaults implement
+// EncodedVault, their encoded variants. Modified times are not compared.
+func DiffVaults(oldVault Vault, newVault Vault) ([]VaultChange, error) {
+	oldFiles, err := vaultFiles(oldVault)
+	if err != nil {
+		return nil, err
+	}
+	newFiles, err := vaultFiles(newVault)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []VaultChange
+	for name := range oldFiles {
+		if _, ok := newFiles[name]; !ok {
+			changes = append(changes, VaultChange{Type: VaultChangeRemoved, Path: name})
+		}
+	}
+
+	for name := range newFiles {
+		if _, ok := oldFiles[name]; !ok {
+			changes = append(changes, VaultChange{Type: VaultChangeAdded, Path: name})
+			continue
+		}
+
+		same, err := sameVaultFile(oldVault, newVault, name)
+		if err != nil {
+			return nil, err
+		}
+		if !same {
+			changes = append(changes, VaultChange{Type: VaultChangeModified, Path: name})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes, nil
+}
+
+func vaultFiles(v Vault) (map[string]struct{}, error) {
+	files := map[string]struct{}{}
+	err := Walk(v, filesystemRootPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			files[path] = struct{}{}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+func sameVaultFile(oldVault Vault, newVault Vault, name string) (bool, error) {
+	oldData, err := oldVault.ReadFile(name)
+	if err != nil {
+		return false, err
+	}
+	newData, err := newVault.ReadFile(name)
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(oldData, newData) {
+		return false, nil
+	}
+
+	oldEncoded, oldOK := oldVault.(EncodedVault)
+	newEncoded, newOK := newVault.(EncodedVault)
+	if !oldOK || !newOK {
+		return true, nil
+	}
+
+	oldEncodings, err := oldEncoded.FileEncodings(name)
+	if err != nil {
+		return false, err
+	}
+	newEncodings, err := newEncoded.FileEncodings(name)
+	if err != nil {
+		return false, err
+	}
+	if strings.Join(oldEncodings, ",") != strings.Join(newEncodings, ",") {
+		return false, nil
+	}
+
+	for _, encoding := range oldEncodings {
+		oldData, err = oldEncoded.ReadFileEncoding(name, encoding)
+		if err != nil {
+			return false, err
+		}
+		newData, err = newEncoded.ReadFileEncoding(name, encoding)
+		if err != nil {
+			return false, err
+		}
+		if !bytes.Equal(oldData, newData) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,70 @@
+package goblin
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffVaults(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	build := func(files map[string]string, opts ...MemoryBuilderOption) *MemoryBuilder {
+		require.NoError(t, os.RemoveAll(td))
+		writeTestFiles(t, td, files)
+
+		b := NewMemoryBuilder(opts...)
+		require.NoError(t, b.IncludeDirs(td, []string{"."}))
+		return b
+	}
+
+	appJS := strings.Repeat("console.log('app');\n", 100)
+	oldVault := build(map[string]string{
+		"index.html":    "index",
+		"about.html":    "about",
+		"static/app.js": appJS,
+	}).v
+
+	t.Run("same files", func(t *testing.T) {
+		changes, err := build(map[string]string{
+			"index.html":    "index",
+			"about.html":    "about",
+			"static/app.js": appJS,
+		}).Diff(oldVault)
+		require.NoError(t, err)
+		assert.Empty(t, changes)
+	})
+
+	t.Run("added removed and changed files", func(t *testing.T) {
+		changes, err := build(map[string]string{
+			"index.html":     "new index",
+			"static/app.js":  appJS,
+			"static/app.css": "css",
+		}).Diff(oldVault)
+		require.NoError(t, err)
+		assert.Equal(t, []VaultChange{
+			{Type: VaultChangeRemoved, Path: "about.html"},
+			{Type: VaultChangeModified, Path: "index.html"},
+			{Type: VaultChangeAdded, Path: "static/app.css"},
+		}, changes)
+		assert.Equal(t, "changed: index.html", changes[1].String())
+	})
+
+	t.Run("changed encodings", func(t *testing.T) {
+		changes, err := build(map[string]string{
+			"index.html":    "index",
+			"about.html":    "about",
+			"static/app.js": appJS,
+		}, MemoryBuilderPrecompress(EncodingGzip)).Diff(oldVault)
+		require.NoError(t, err)
+		assert.Equal(t, []VaultChange{
+			{Type: VaultChangeModified, Path: "static/app.js"},
+		}, changes)
+	})
+}
@@ -3,7 +3,10 @@ package goblin
 import (
 	"fmt"
 	"os"
-	"strings"
+	"os/signal"
+	"reflect"
+	"sync"
+	"time"
 )
 
 // SelectOption is an option used when creating a vault selector.
@@ -12,69 +15,122 @@ type SelectOption func(*VaultSelector)
 // SelectVaultMethod is the method signature a vault selector option needs to implement.
 type SelectVaultMethod func() Vault
 
-// SelectEnvBool will use the provided vault if the given environment variable is
-// true. Accepted truthy values are TRUE, T, YES, Y, and 1. Values are case-insensitive.
-func SelectEnvBool(envName string, v Vault) SelectOption {
+// SelectReselectInterval causes the vault selector to run its selectors again when
+// the vault is accessed and the current selection is older than the provided
+// interval. An interval of zero re-runs the selectors on every access.
+func SelectReselectInterval(interval time.Duration) SelectOption {
 	return func(vs *VaultSelector) {
-		vs.AppendSelector(func() Vault {
-			val := strings.TrimSpace(strings.ToUpper(os.Getenv(envName)))
-			if val == "TRUE" || val == "T" ||
-				val == "YES" || val == "Y" ||
-				val == "1" {
-				return v
-			}
+		vs.reselect = true
+		vs.reselectInterval = interval
+	}
+}
 
-			return nil
-		})
+// SelectOnChange registers a function to be called when the vault selected by the
+// vault selector changes, including the first time a vault is selected. When no
+// vault was previously selected, prev will be the zero value. The function may use
+// the vault selector, such as to reload data from the newly selected vault.
+func SelectOnChange(onChange func(prev VaultSelection, cur VaultSelection)) SelectOption {
+	return func(vs *VaultSelector) {
+		vs.onChange = append(vs.onChange, onChange)
 	}
 }
 
+// SelectOnError registers a function to be called when running the selectors again
+// fails to select a vault, such as a reselect triggered by ReselectOnSignal. The
+// previously selected vault keeps being used until a later selection succeeds.
+func SelectOnError(onError func(err error)) SelectOption {
+	return func(vs *VaultSelector) {
+		vs.onError = append(vs.onError, onError)
+	}
+}
+
+// SelectEnvBool will use the provided vault if the given environment variable is
+// true. Accepted truthy values are TRUE, T, YES, Y, and 1. Values are case-insensitive.
+func SelectEnvBool(envName string, v Vault) SelectOption {
+	return SelectWhen(EnvBool(envName), v)
+}
+
 // SelectEnvNotEmpty will use the provided vault if the given environment variable is
 // set with a non-empty value.
 func SelectEnvNotEmpty(envName string, v Vault) SelectOption {
-	return func(vs *VaultSelector) {
-		vs.AppendSelector(func() Vault {
-			val := strings.TrimSpace(os.Getenv(envName))
-			if val != "" {
-				return v
-			}
-
-			return nil
-		})
-	}
+	return SelectWhen(EnvNotEmpty(envName), v)
 }
 
 // SelectPath will use the provided vault if the given path exists on disk.
 func SelectPath(path string, v Vault) SelectOption {
-	return func(vs *VaultSelector) {
-		vs.AppendSelector(func() Vault {
-			_, err := os.Stat(path)
-			if err != nil {
-				return nil
-			}
-
-			return v
-		})
-	}
+	return SelectWhen(PathExists(path), v)
 }
 
 // SelectDefault will always return the provided vault.
 func SelectDefault(v Vault) SelectOption {
 	return func(vs *VaultSelector) {
-		vs.AppendSelector(func() Vault {
+		vs.AppendNamedSelector("default", func() Vault {
 			return v
 		})
 	}
 }
 
+// VaultSelection describes the vault chosen by a vault selector.
+type VaultSelection struct {
+	// Index is the position of the winning selector in the order selectors
+	// were added to the vault selector.
+	Index int
+	// Description is a human readable description of the winning selector.
+	Description string
+	// Vault is the selected vault.
+	Vault Vault
+}
+
+type vaultSelectorEntry struct {
+	description string
+	selectVault SelectVaultMethod
+	checks      []VaultCheck
+}
+
+// selectAndCheck runs the selector and any checks for the selected vault, returning the
+// reason the selector was rejected if no vault was selected.
+func (e vaultSelectorEntry) selectAndCheck() (Vault, error) {
+	v := e.selectVault()
+	if v == nil {
+		return nil, errSelectorNotMatched
+	}
+
+	for _, check := range e.checks {
+		if err := check(v); err != nil {
+			return nil, fmt.Errorf("%s failed validation: %w", v, err)
+		}
+	}
+
+	return v, nil
+}
+
 // VaultSelector is a vault that will use a vault selected by one of the added
 // selectors.
+//
+// By default the first successful selection is used for the lifetime of the
+// vault selector. Use SelectReselectInterval or Reselect to pick up changes
+// in the environment at runtime.
 type VaultSelector struct {
-	selectedVault Vault
-	vaultOptions  []SelectVaultMethod
+	lock             sync.Mutex
+	selected         *VaultSelection
+	selectedAt       time.Time
+	report           SelectionReport
+	vaultOptions     []vaultSelectorEntry
+	reselect         bool
+	reselectInterval time.Duration
+
+	// checks are added to each selector appended while applying a SelectValidated
+	// option.
+	checks []VaultCheck
+
+	changeLock sync.Mutex
+	onChange   []func(VaultSelection, VaultSelection)
+	onError    []func(error)
 }
 
 var _ Vault = &VaultSelector{}
+var _ WatchVault = &VaultSelector{}
+var _ EncodedVault = &VaultSelector{}
 
 // NewVaultSelector creates a new vault selector.
 func NewVaultSelector(opts ...SelectOption) *VaultSelector {
@@ -89,19 +145,188 @@ func NewVaultSelector(opts ...SelectOption) *VaultSelector {
 // GetVault returns the vault to be used by the vault selector using the
 // currently provided selectors.
 func (vs *VaultSelector) GetVault() (Vault, error) {
-	if vs.selectedVault != nil {
-		return vs.selectedVault, nil
+	selection, err := vs.Selection()
+	if err != nil {
+		return nil, err
 	}
 
-	for _, selectVault := range vs.vaultOptions {
-		v := selectVault()
-		if v != nil {
-			vs.selectedVault = v
-			return v, nil
+	return selection.Vault, nil
+}
+
+// Selection returns information about the vault currently selected by the
+// vault selector, selecting one if needed. If the selection has expired and
+// selecting again fails, the previous selection is returned.
+func (vs *VaultSelector) Selection() (VaultSelection, error) {
+	vs.lock.Lock()
+	if vs.selected != nil && !vs.selectionExpired() {
+		selection := *vs.selected
+		vs.lock.Unlock()
+		return selection, nil
+	}
+	vs.lock.Unlock()
+
+	selection, err := vs.runSelectors()
+	if err != nil {
+		vs.lock.Lock()
+		defer vs.lock.Unlock()
+		if vs.selected != nil {
+			return *vs.selected, nil
 		}
+		return VaultSelection{}, err
 	}
 
-	return nil, fmt.Errorf("no vault could be selected")
+	return selection, nil
+}
+
+// Report returns a report of the selectors evaluated the last time the vault
+// selector selected a vault, including why each rejected selector wasn't used.
+// Selectors after the selected one are not evaluated and are not included. If
+// a vault hasn't been selected yet, the selectors are run first.
+func (vs *VaultSelector) Report() SelectionReport {
+	_, _ = vs.Selection()
+
+	vs.lock.Lock()
+	defer vs.lock.Unlock()
+
+	report := make(SelectionReport, len(vs.report))
+	copy(report, vs.report)
+
+	return report
+}
+
+// Reselect runs the vault selectors again and returns the newly selected vault.
+// This can be called in response to an external trigger, such as a SIGHUP, to
+// pick up changes in the environment. If no vault can be selected, an error is
+// returned and the previously selected vault is kept.
+func (vs *VaultSelector) Reselect() (Vault, error) {
+	selection, err := vs.runSelectors()
+	if err != nil {
+		return nil, err
+	}
+
+	return selection.Vault, nil
+}
+
+// ReselectOnSignal calls Reselect every time one of the provided signals is
+// received by the process. Errors are passed to any functions registered with
+// SelectOnError. The returned function stops listening for signals.
+func (vs *VaultSelector) ReselectOnSignal(sigs ...os.Signal) func() {
+	sigChan := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigChan, sigs...)
+
+	go func() {
+		for {
+			select {
+			case <-sigChan:
+				_, _ = vs.Reselect()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var stopOnce sync.Once
+	return func() {
+		stopOnce.Do(func() {
+			signal.Stop(sigChan)
+			close(done)
+		})
+	}
+}
+
+func (vs *VaultSelector) selectionExpired() bool {
+	if !vs.reselect {
+		return false
+	}
+
+	return time.Since(vs.selectedAt) >= vs.reselectInterval
+}
+
+func (vs *VaultSelector) runSelectors() (VaultSelection, error) {
+	// Callbacks are called after the selection is recorded and without holding a
+	// lock, so they can use the vault selector, such as to read from the new vault.
+	prev, cur := vs.updateSelection()
+
+	if cur == nil {
+		err := fmt.Errorf("no vault could be selected")
+		for _, onError := range vs.onError {
+			onError(err)
+		}
+		return VaultSelection{}, err
+	}
+
+	if prev == nil || prev.Index != cur.Index || !sameVault(prev.Vault, cur.Vault) {
+		var prevSelection VaultSelection
+		if prev != nil {
+			prevSelection = *prev
+		}
+
+		for _, onChange := range vs.onChange {
+			onChange(prevSelection, *cur)
+		}
+	}
+
+	return *cur, nil
+}
+
+// updateSelection runs the selectors and records the result, returning the previous
+// selection and the new one. The new selection is nil if no vault was selected.
+func (vs *VaultSelector) updateSelection() (*VaultSelection, *VaultSelection) {
+	vs.changeLock.Lock()
+	defer vs.changeLock.Unlock()
+
+	vs.lock.Lock()
+	options := make([]vaultSelectorEntry, len(vs.vaultOptions))
+	copy(options, vs.vaultOptions)
+	vs.lock.Unlock()
+
+	var cur *VaultSelection
+	var report SelectionReport
+	for idx, option := range options {
+		v, err := option.selectAndCheck()
+		report = append(report, SelectionAttempt{
+			Index:       idx,
+			Description: option.description,
+			Selected:    err == nil,
+			Err:         err,
+		})
+		if err == nil {
+			cur = &VaultSelection{
+				Index:       idx,
+				Description: option.description,
+				Vault:       v,
+			}
+			break
+		}
+	}
+
+	// A failed selection keeps the previous vault, so a temporary problem in the
+	// environment doesn't leave the vault selector without a vault.
+	vs.lock.Lock()
+	prev := vs.selected
+	if cur != nil {
+		vs.selected = cur
+	}
+	vs.selectedAt = time.Now()
+	vs.report = report
+	vs.lock.Unlock()
+
+	return prev, cur
+}
+
+// sameVault reports whether two vaults are the same vault. Vaults with types that
+// can't be compared are assumed to be the same if their types match.
+func sameVault(a Vault, b Vault) bool {
+	aType, bType := reflect.TypeOf(a), reflect.TypeOf(b)
+	if aType != bType {
+		return false
+	}
+	if !aType.Comparable() {
+		return true
+	}
+
+	return a == b
 }
 
 func (vs *VaultSelector) String() string {
@@ -116,8 +341,32 @@ func (vs *VaultSelector) String() string {
 // AppendSelector adds an additional vault selector to the end of the vault
 // selector list.
 func (vs *VaultSelector) AppendSelector(svm SelectVaultMethod) {
-	vs.vaultOptions = append(vs.vaultOptions, svm)
-	vs.selectedVault = nil
+	vs.appendEntry("", svm)
+}
+
+// AppendNamedSelector adds an additional vault selector to the end of the vault
+// selector list with a description used when reporting which selector was chosen.
+func (vs *VaultSelector) AppendNamedSelector(description string, svm SelectVaultMethod) {
+	vs.appendEntry(description, svm)
+}
+
+// appendEntry adds a selector to the end of the vault selector list with any checks
+// from the SelectValidated options being applied. Selectors without a description
+// are described by their position in the list.
+func (vs *VaultSelector) appendEntry(description string, svm SelectVaultMethod) {
+	vs.lock.Lock()
+	defer vs.lock.Unlock()
+
+	if description == "" {
+		description = fmt.Sprintf("selector %d", len(vs.vaultOptions))
+	}
+
+	vs.vaultOptions = append(vs.vaultOptions, vaultSelectorEntry{
+		description: description,
+		selectVault: svm,
+		checks:      append([]VaultCheck(nil), vs.checks...),
+	})
+	vs.selected = nil
 }
 
 // Open will open the file at the provided path from the selected vault.
@@ -176,3 +425,49 @@ func (vs *VaultSelector) ReadFile(name string) ([]byte, error) {
 
 	return v.ReadFile(name)
 }
+
+// Watch returns a Watcher for files matching the provided pattern in the selected
+// vault. The selected vault must implement WatchVault.
+func (vs *VaultSelector) Watch(pattern string) (*Watcher, error) {
+	v, err := vs.GetVault()
+	if err != nil {
+		return nil, err
+	}
+
+	if wv, ok := v.(WatchVault); ok {
+		return wv.Watch(pattern)
+	}
+
+	return nil, fmt.Errorf("not supported")
+}
+
+// FileEncodings returns the encodings available for the file at the given path in
+// the selected vault. If the selected vault doesn't implement EncodedVault, no
+// encodings are returned.
+func (vs *VaultSelector) FileEncodings(name string) ([]string, error) {
+	v, err := vs.GetVault()
+	if err != nil {
+		return nil, err
+	}
+
+	if ev, ok := v.(EncodedVault); ok {
+		return ev.FileEncodings(name)
+	}
+
+	return nil, nil
+}
+
+// ReadFileEncoding returns the contents of the variant of the file at the given path
+// with the provided encoding from the selected vault.
+func (vs *VaultSelector) ReadFileEncoding(name string, encoding string) ([]byte, error) {
+	v, err := vs.GetVault()
+	if err != nil {
+		return nil, err
+	}
+
+	if ev, ok := v.(EncodedVault); ok {
+		return ev.ReadFileEncoding(name, encoding)
+	}
+
+	return nil, os.ErrNotExist
+}
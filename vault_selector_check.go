@@ -0,0 +1,165 @@
+package goblin
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errSelectorNotMatched = errors.New("selector did not match")
+
+// SelectionAttempt describes the result of evaluating a single selector when a
+// vault selector selects a vault.
+type SelectionAttempt struct {
+	// Index is the position of the selector in the order selectors were added
+	// to the vault selector.
+	Index int
+	// Description is a human readable description of the selector.
+	Description string
+	// Selected is true if this selector's vault was selected.
+	Selected bool
+	// Err is the reason the selector was rejected, or nil if it was selected.
+	Err error
+}
+
+// SelectionReport is the list of selectors evaluated when a vault selector selected
+// a vault, in the order they were evaluated.
+type SelectionReport []SelectionAttempt
+
+func (r SelectionReport) String() string {
+	var lines []string
+	for _, attempt := range r {
+		status := "selected"
+		if !attempt.Selected {
+			status = "rejected: " + attempt.Err.Error()
+		}
+
+		lines = append(lines, fmt.Sprintf("%d. %s: %s", attempt.Index, attempt.Description, status))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// VaultCheck validates a vault selected by a vault selector before it's used. If an
+// error is returned the vault is rejected and the next selector is evaluated.
+type VaultCheck func(Vault) error
+
+// SelectValidated wraps the provided selector option so any vault it selects must
+// pass all of the provided checks before it's used. If a check fails, the vault
+// selector falls through to the next selector and the failure is recorded in the
+// vault selector's report. Options that don't add selectors, such as SelectOnChange,
+// are applied to the vault selector as usual.
+func SelectValidated(opt SelectOption, checks ...VaultCheck) SelectOption {
+	return func(vs *VaultSelector) {
+		vs.lock.Lock()
+		prevChecks := vs.checks
+		vs.checks = append(append([]VaultCheck(nil), prevChecks...), checks...)
+		vs.lock.Unlock()
+
+		opt(vs)
+
+		vs.lock.Lock()
+		vs.checks = prevChecks
+		vs.lock.Unlock()
+	}
+}
+
+// CheckFiles requires all of the provided paths to exist in the vault as files.
+func CheckFiles(names ...string) VaultCheck {
+	return func(v Vault) error {
+		for _, name := range names {
+			fInfo, err := v.Stat(name)
+			if err != nil {
+				return fmt.Errorf("required file %s: %w", name, err)
+			}
+			if fInfo.IsDir() {
+				return fmt.Errorf("required file %s is a directory", name)
+			}
+		}
+
+		return nil
+	}
+}
+
+// CheckManifest requires every file listed in the manifest file at the provided path
+// in the vault to exist. The manifest contains one path per line and uses the same
+// format as the output of sha256sum, so a line may also start with the expected
+// hex-encoded SHA-256 hash of the file's contents followed by whitespace. Everything
+// after the whitespace is the path, so paths may contain spaces. Blank lines and
+// lines starting with # are ignored, and a hash without a path fails the check.
+func CheckManifest(manifestName string) VaultCheck {
+	return func(v Vault) error {
+		manifestData, err := v.ReadFile(manifestName)
+		if err != nil {
+			return fmt.Errorf("manifest %s: %w", manifestName, err)
+		}
+
+		lineNum := 0
+		scanner := bufio.NewScanner(bytes.NewReader(manifestData))
+		for scanner.Scan() {
+			lineNum++
+
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			expectedHash, name, err := parseManifestLine(line)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", manifestName, lineNum, err)
+			}
+
+			data, err := v.ReadFile(name)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %s: %w", manifestName, lineNum, name, err)
+			}
+
+			if expectedHash != "" {
+				hash := sha256.Sum256(data)
+				if hex.EncodeToString(hash[:]) != expectedHash {
+					return fmt.Errorf("%s:%d: %s does not match manifest hash", manifestName, lineNum, name)
+				}
+			}
+		}
+
+		return scanner.Err()
+	}
+}
+
+// parseManifestLine returns the expected hash, if any, and the path from a line in a
+// manifest. A line starting with a hex-encoded SHA-256 hash followed by whitespace is a
+// hash and path, like the output of sha256sum. Otherwise the whole line is the path, so
+// a path with a first word that looks like hex, such as "add new.txt", isn't a hash.
+func parseManifestLine(line string) (string, string, error) {
+	sepIdx := strings.IndexAny(line, " \t")
+	if sepIdx != hex.EncodedLen(sha256.Size) || !isHex(line[:sepIdx]) {
+		return "", line, nil
+	}
+
+	hash := strings.ToLower(line[:sepIdx])
+
+	// sha256sum uses * to mark files read in binary mode
+	name := strings.TrimPrefix(strings.TrimLeft(line[sepIdx:], " \t"), "*")
+	if name == "" {
+		return "", "", fmt.Errorf("missing path after hash %s", hash)
+	}
+
+	return hash, name, nil
+}
+
+func isHex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,216 @@
+package goblin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCheckVault() *MemoryVault {
+	v := NewMemoryVault()
+	_ = v.WriteFile("index.html", bytes.NewBufferString("<html></html>"))
+	_ = v.WriteFile("static/app.js", bytes.NewBufferString("app()"))
+	return v
+}
+
+func TestCheckFiles(t *testing.T) {
+	v := newTestCheckVault()
+
+	assert.NoError(t, CheckFiles("index.html", "static/app.js")(v))
+	assert.EqualError(t,
+		CheckFiles("index.html", "missing.html")(v),
+		"required file missing.html: file does not exist",
+	)
+	assert.EqualError(t,
+		CheckFiles("static")(v),
+		"required file static is a directory",
+	)
+}
+
+func TestCheckManifest(t *testing.T) {
+	jsHash := sha256.Sum256([]byte("app()"))
+
+	t.Run("valid manifest", func(t *testing.T) {
+		v := newTestCheckVault()
+		_ = v.WriteFile("MANIFEST", bytes.NewBufferString(
+			"# Required files\n"+
+				"index.html\n"+
+				hex.EncodeToString(jsHash[:])+" *static/app.js\n",
+		))
+
+		assert.NoError(t, CheckManifest("MANIFEST")(v))
+	})
+
+	t.Run("missing manifest", func(t *testing.T) {
+		v := newTestCheckVault()
+		assert.EqualError(t, CheckManifest("MANIFEST")(v), "manifest MANIFEST: file does not exist")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		v := newTestCheckVault()
+		_ = v.WriteFile("MANIFEST", bytes.NewBufferString("index.html\nmissing.html\n"))
+
+		assert.EqualError(t, CheckManifest("MANIFEST")(v), "MANIFEST:2: missing.html: file does not exist")
+	})
+
+	t.Run("paths with spaces", func(t *testing.T) {
+		v := newTestCheckVault()
+		_ = v.WriteFile("static/my app.js", bytes.NewBufferString("app()"))
+		_ = v.WriteFile("MANIFEST", bytes.NewBufferString(
+			"static/my app.js\n"+
+				hex.EncodeToString(jsHash[:])+"  static/my app.js\n"+
+				hex.EncodeToString(jsHash[:])+" *static/my app.js\n",
+		))
+
+		assert.NoError(t, CheckManifest("MANIFEST")(v))
+	})
+
+	t.Run("paths starting with hex words", func(t *testing.T) {
+		v := newTestCheckVault()
+		_ = v.WriteFile("cafe notes.txt", bytes.NewBufferString("notes"))
+		_ = v.WriteFile("add new.txt", bytes.NewBufferString("new"))
+		_ = v.WriteFile("MANIFEST", bytes.NewBufferString("cafe notes.txt\nadd new.txt\n"))
+
+		assert.NoError(t, CheckManifest("MANIFEST")(v))
+	})
+
+	t.Run("malformed lines", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			line     string
+			expected string
+		}{
+			{
+				name:     "missing path",
+				line:     hex.EncodeToString(jsHash[:]) + "  *",
+				expected: "MANIFEST:1: missing path after hash " + hex.EncodeToString(jsHash[:]),
+			},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				v := newTestCheckVault()
+				_ = v.WriteFile("MANIFEST", bytes.NewBufferString(test.line+"\n"))
+
+				assert.EqualError(t, CheckManifest("MANIFEST")(v), test.expected)
+			})
+		}
+	})
+
+	t.Run("hash mismatch", func(t *testing.T) {
+		v := newTestCheckVault()
+		_ = v.WriteFile("MANIFEST", bytes.NewBufferString(
+			hex.EncodeToString(jsHash[:])+"  index.html\n",
+		))
+
+		assert.EqualError(t, CheckManifest("MANIFEST")(v), "MANIFEST:1: index.html does not match manifest hash")
+	})
+}
+
+func TestSelectValidated(t *testing.T) {
+	t.Run("falls through on failed check", func(t *testing.T) {
+		brokenVault := NewMemoryVault()
+		validVault := newTestCheckVault()
+		defaultVault := NewMemoryVault()
+
+		vs := NewVaultSelector(
+			SelectValidated(SelectDefault(brokenVault), CheckFiles("index.html")),
+			SelectValidated(SelectDefault(validVault), CheckFiles("index.html")),
+			SelectDefault(defaultVault),
+		)
+
+		selection, err := vs.Selection()
+		require.NoError(t, err)
+		assert.Equal(t, 1, selection.Index)
+		assert.Same(t, validVault, selection.Vault)
+	})
+
+	t.Run("report", func(t *testing.T) {
+		vs := NewVaultSelector(
+			SelectWhen(PredicateFunc("never", func() bool { return false }), NewMemoryVault()),
+			SelectValidated(SelectDefault(NewMemoryVault()), CheckFiles("index.html")),
+			SelectDefault(newTestCheckVault()),
+			SelectDefault(NewMemoryVault()),
+		)
+
+		report := vs.Report()
+		require.Len(t, report, 3)
+
+		assert.Equal(t, 0, report[0].Index)
+		assert.Equal(t, "never", report[0].Description)
+		assert.False(t, report[0].Selected)
+		assert.Equal(t, errSelectorNotMatched, report[0].Err)
+
+		assert.False(t, report[1].Selected)
+		assert.EqualError(t, report[1].Err, "Memory Vault failed validation: required file index.html: file does not exist")
+
+		assert.True(t, report[2].Selected)
+		assert.NoError(t, report[2].Err)
+
+		assert.Equal(t,
+			"0. never: rejected: selector did not match\n"+
+				"1. default: rejected: Memory Vault failed validation: required file index.html: file does not exist\n"+
+				"2. default: selected",
+			report.String(),
+		)
+	})
+
+	t.Run("default descriptions", func(t *testing.T) {
+		vs := NewVaultSelector(
+			SelectWhen(PredicateFunc("never", func() bool { return false }), NewMemoryVault()),
+			SelectValidated(func(vs *VaultSelector) {
+				vs.AppendSelector(func() Vault { return NewMemoryVault() })
+			}, CheckFiles("index.html")),
+			SelectDefault(NewMemoryVault()),
+		)
+
+		report := vs.Report()
+		require.Len(t, report, 3)
+		assert.Equal(t, "selector 1", report[1].Description)
+	})
+
+	t.Run("other options are applied", func(t *testing.T) {
+		var changes int
+		validVault := newTestCheckVault()
+		vs := NewVaultSelector(
+			SelectValidated(SelectOnChange(func(prev VaultSelection, cur VaultSelection) {
+				changes++
+			}), CheckFiles("index.html")),
+			SelectValidated(SelectDefault(validVault), CheckFiles("index.html")),
+		)
+
+		v, err := vs.GetVault()
+		require.NoError(t, err)
+		assert.Same(t, validVault, v)
+		assert.Equal(t, 1, changes)
+	})
+
+	t.Run("checks only apply to wrapped selectors", func(t *testing.T) {
+		defaultVault := NewMemoryVault()
+		vs := NewVaultSelector(
+			SelectValidated(SelectDefault(NewMemoryVault()), CheckFiles("index.html")),
+			SelectDefault(defaultVault),
+		)
+
+		v, err := vs.GetVault()
+		require.NoError(t, err)
+		assert.Same(t, defaultVault, v)
+	})
+
+	t.Run("no valid vaults", func(t *testing.T) {
+		vs := NewVaultSelector(
+			SelectValidated(SelectDefault(NewMemoryVault()), CheckFiles("index.html")),
+		)
+
+		_, err := vs.GetVault()
+		assert.Error(t, err)
+
+		report := vs.Report()
+		require.Len(t, report, 1)
+		assert.False(t, report[0].Selected)
+	})
+}
@@ -1,8 +1,10 @@
 package goblin_test
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/aphistic/goblin"
 )
@@ -97,3 +99,92 @@ func ExampleSelectDefault() {
 	fmt.Printf("%s\n", vs)
 	// Output: Vault Selector (Filesystem Vault (/))
 }
+
+func ExampleVaultSelector_Selection() {
+	const envKey = "USE_SELECTION_EXAMPLE"
+	os.Unsetenv(envKey)
+
+	vs := goblin.NewVaultSelector(
+		goblin.SelectEnvBool(envKey, goblin.NewFilesystemVault("/")),
+		goblin.SelectDefault(goblin.NewMemoryVault()),
+		goblin.SelectOnChange(func(prev goblin.VaultSelection, cur goblin.VaultSelection) {
+			fmt.Printf("Switched to %s\n", cur.Vault)
+		}),
+	)
+
+	selection, _ := vs.Selection()
+	fmt.Printf("Selector %d (%s) won\n", selection.Index, selection.Description)
+
+	// Re-run the selectors after the environment changes, for example
+	// when receiving a SIGHUP.
+	os.Setenv(envKey, "true")
+	_, _ = vs.Reselect()
+
+	// Output:
+	// Switched to Memory Vault
+	// Selector 1 (default) won
+	// Switched to Filesystem Vault (/)
+}
+
+func ExampleSelectWhen() {
+	const devKey = "USE_SELECT_WHEN_DEV"
+	os.Setenv(devKey, "true")
+
+	memVault := goblin.NewMemoryVault()
+	fsVault := goblin.NewFilesystemVault("/usr")
+
+	// Only use the filesystem vault when in development mode and the
+	// directory exists, otherwise use the embedded vault.
+	vs := goblin.NewVaultSelector(
+		goblin.SelectWhen(
+			goblin.And(
+				goblin.EnvBool(devKey),
+				goblin.PathIsDir("/usr"),
+			),
+			fsVault,
+		),
+		goblin.SelectDefault(memVault),
+	)
+
+	selection, _ := vs.Selection()
+	fmt.Printf("%s: %s\n", selection.Description, selection.Vault)
+	// Output: (environment variable USE_SELECT_WHEN_DEV is true and path /usr is a directory): Filesystem Vault (/usr)
+}
+
+func ExampleSelectFlag() {
+	fs := flag.NewFlagSet("app", flag.ExitOnError)
+	fs.String("assets-dir", "", "Serve assets from this directory instead of the embedded ones")
+
+	// The flag is read when the vault is selected, so the selector can be
+	// created before the flags are parsed.
+	vs := goblin.NewVaultSelector(
+		goblin.SelectFlag(fs, "assets-dir"),
+		goblin.SelectDefault(goblin.NewMemoryVault()),
+	)
+
+	_ = fs.Parse([]string{"--assets-dir=/usr"})
+
+	fmt.Printf("%s\n", vs)
+	// Output: Vault Selector (Filesystem Vault (/usr))
+}
+
+func ExampleSelectValidated() {
+	// The override vault exists but is missing index.html, so it's rejected
+	// and the embedded vault is used instead.
+	overrideVault := goblin.NewMemoryVault()
+	embeddedVault := goblin.NewMemoryVault()
+	_ = embeddedVault.WriteFile("index.html", strings.NewReader("<html></html>"))
+
+	vs := goblin.NewVaultSelector(
+		goblin.SelectValidated(
+			goblin.SelectDefault(overrideVault),
+			goblin.CheckFiles("index.html"),
+		),
+		goblin.SelectDefault(embeddedVault),
+	)
+
+	fmt.Printf("%s\n", vs.Report())
+	// Output:
+	// 0. default: rejected: Memory Vault failed validation: required file index.html: file does not exist
+	// 1. default: selected
+}
@@ -0,0 +1,201 @@
+package goblin
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime/debug"
+	"strings"
+)
+
+// SelectPredicate is a condition used by a vault selector to decide whether a
+// vault should be selected.
+type SelectPredicate interface {
+	// Match returns true if the condition is currently met.
+	Match() bool
+
+	fmt.Stringer
+}
+
+type selectPredicate struct {
+	description string
+	match       func() bool
+}
+
+func (p *selectPredicate) Match() bool {
+	return p.match()
+}
+
+func (p *selectPredicate) String() string {
+	return p.description
+}
+
+// SelectWhen will use the provided vault if the given predicate matches.
+func SelectWhen(pred SelectPredicate, v Vault) SelectOption {
+	return func(vs *VaultSelector) {
+		vs.AppendNamedSelector(pred.String(), func() Vault {
+			if pred.Match() {
+				return v
+			}
+
+			return nil
+		})
+	}
+}
+
+// PredicateFunc creates a predicate that matches when the provided function
+// returns true. The description is used when reporting the selected vault.
+func PredicateFunc(description string, match func() bool) SelectPredicate {
+	return &selectPredicate{
+		description: description,
+		match:       match,
+	}
+}
+
+// EnvBool matches if the given environment variable is true. Accepted truthy values
+// are TRUE, T, YES, Y, and 1. Values are case-insensitive.
+func EnvBool(envName string) SelectPredicate {
+	return PredicateFunc(
+		fmt.Sprintf("environment variable %s is true", envName),
+		func() bool {
+			val := strings.TrimSpace(strings.ToUpper(os.Getenv(envName)))
+			return val == "TRUE" || val == "T" ||
+				val == "YES" || val == "Y" ||
+				val == "1"
+		},
+	)
+}
+
+// EnvEquals matches if the given environment variable is set to the provided value.
+// Leading and trailing whitespace in the environment variable is ignored.
+func EnvEquals(envName string, value string) SelectPredicate {
+	return PredicateFunc(
+		fmt.Sprintf("environment variable %s is %q", envName, value),
+		func() bool {
+			return strings.TrimSpace(os.Getenv(envName)) == value
+		},
+	)
+}
+
+// EnvNotEmpty matches if the given environment variable is set with a non-empty value.
+func EnvNotEmpty(envName string) SelectPredicate {
+	return PredicateFunc(
+		fmt.Sprintf("environment variable %s is not empty", envName),
+		func() bool {
+			return strings.TrimSpace(os.Getenv(envName)) != ""
+		},
+	)
+}
+
+// PathExists matches if the given path exists on disk.
+func PathExists(path string) SelectPredicate {
+	return PredicateFunc(
+		fmt.Sprintf("path %s exists", path),
+		func() bool {
+			_, err := os.Stat(path)
+			return err == nil
+		},
+	)
+}
+
+// PathIsDir matches if the given path exists on disk and is a directory.
+func PathIsDir(path string) SelectPredicate {
+	return PredicateFunc(
+		fmt.Sprintf("path %s is a directory", path),
+		func() bool {
+			fInfo, err := os.Stat(path)
+			return err == nil && fInfo.IsDir()
+		},
+	)
+}
+
+// FileContains matches if the file at the given path exists on disk and contains
+// the provided value.
+func FileContains(path string, value string) SelectPredicate {
+	return PredicateFunc(
+		fmt.Sprintf("file %s contains %q", path, value),
+		func() bool {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return false
+			}
+
+			return bytes.Contains(data, []byte(value))
+		},
+	)
+}
+
+// BuildInfo matches if the provided function returns true for the build information
+// embedded in the running binary. It never matches if the binary was built without
+// module support.
+func BuildInfo(description string, check func(*debug.BuildInfo) bool) SelectPredicate {
+	return PredicateFunc(description, func() bool {
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return false
+		}
+
+		return check(info)
+	})
+}
+
+// BuildIsDevel matches if the main module of the running binary doesn't have a
+// release version, such as when it's built with `go run` or `go build` from a
+// local checkout instead of being installed at a specific version.
+func BuildIsDevel() SelectPredicate {
+	return BuildInfo("main module is a development build", func(info *debug.BuildInfo) bool {
+		return info.Main.Version == "" || info.Main.Version == "(devel)"
+	})
+}
+
+// And matches if all of the provided predicates match. An empty And always matches.
+func And(preds ...SelectPredicate) SelectPredicate {
+	return PredicateFunc(
+		joinPredicates(preds, "and"),
+		func() bool {
+			for _, pred := range preds {
+				if !pred.Match() {
+					return false
+				}
+			}
+
+			return true
+		},
+	)
+}
+
+// Or matches if any of the provided predicates match. An empty Or never matches.
+func Or(preds ...SelectPredicate) SelectPredicate {
+	return PredicateFunc(
+		joinPredicates(preds, "or"),
+		func() bool {
+			for _, pred := range preds {
+				if pred.Match() {
+					return true
+				}
+			}
+
+			return false
+		},
+	)
+}
+
+// Not matches if the provided predicate does not match.
+func Not(pred SelectPredicate) SelectPredicate {
+	return PredicateFunc(
+		fmt.Sprintf("not %s", pred),
+		func() bool {
+			return !pred.Match()
+		},
+	)
+}
+
+func joinPredicates(preds []SelectPredicate, op string) string {
+	var descs []string
+	for _, pred := range preds {
+		descs = append(descs, pred.String())
+	}
+
+	return "(" + strings.Join(descs, " "+op+" ") + ")"
+}
@@ -0,0 +1,131 @@
+package goblin
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvPredicates(t *testing.T) {
+	const envKey = "GOBLIN_TEST_ENV_PREDICATE"
+	defer os.Unsetenv(envKey)
+
+	t.Run("env bool", func(t *testing.T) {
+		p := EnvBool(envKey)
+		assert.Equal(t, "environment variable "+envKey+" is true", p.String())
+
+		for _, val := range []string{"true", "T", "yes", "Y", "1", " TRUE "} {
+			os.Setenv(envKey, val)
+			assert.True(t, p.Match(), val)
+		}
+		for _, val := range []string{"", "false", "0", "no"} {
+			os.Setenv(envKey, val)
+			assert.False(t, p.Match(), val)
+		}
+	})
+
+	t.Run("env equals", func(t *testing.T) {
+		p := EnvEquals(envKey, "production")
+		assert.Equal(t, "environment variable "+envKey+` is "production"`, p.String())
+
+		os.Setenv(envKey, "production")
+		assert.True(t, p.Match())
+		os.Setenv(envKey, "development")
+		assert.False(t, p.Match())
+	})
+
+	t.Run("env not empty", func(t *testing.T) {
+		p := EnvNotEmpty(envKey)
+
+		os.Setenv(envKey, "value")
+		assert.True(t, p.Match())
+		os.Setenv(envKey, "   ")
+		assert.False(t, p.Match())
+	})
+}
+
+func TestPathPredicates(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	filePath := path.Join(td, "file.txt")
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("mode=development"), 0644))
+	missingPath := path.Join(td, "missing")
+
+	t.Run("path exists", func(t *testing.T) {
+		assert.True(t, PathExists(td).Match())
+		assert.True(t, PathExists(filePath).Match())
+		assert.False(t, PathExists(missingPath).Match())
+	})
+
+	t.Run("path is dir", func(t *testing.T) {
+		assert.True(t, PathIsDir(td).Match())
+		assert.False(t, PathIsDir(filePath).Match())
+		assert.False(t, PathIsDir(missingPath).Match())
+	})
+
+	t.Run("file contains", func(t *testing.T) {
+		assert.True(t, FileContains(filePath, "development").Match())
+		assert.False(t, FileContains(filePath, "production").Match())
+		assert.False(t, FileContains(missingPath, "development").Match())
+	})
+}
+
+func TestBuildInfoPredicate(t *testing.T) {
+	var called bool
+	p := BuildInfo("build info", func(info *debug.BuildInfo) bool {
+		called = true
+		return info != nil
+	})
+
+	_, ok := debug.ReadBuildInfo()
+	assert.Equal(t, ok, p.Match())
+	assert.Equal(t, ok, called)
+}
+
+func TestPredicateCombinators(t *testing.T) {
+	yes := PredicateFunc("yes", func() bool { return true })
+	no := PredicateFunc("no", func() bool { return false })
+
+	t.Run("and", func(t *testing.T) {
+		assert.True(t, And(yes, yes).Match())
+		assert.False(t, And(yes, no).Match())
+		assert.True(t, And().Match())
+		assert.Equal(t, "(yes and no)", And(yes, no).String())
+	})
+
+	t.Run("or", func(t *testing.T) {
+		assert.True(t, Or(no, yes).Match())
+		assert.False(t, Or(no, no).Match())
+		assert.False(t, Or().Match())
+		assert.Equal(t, "(no or yes)", Or(no, yes).String())
+	})
+
+	t.Run("not", func(t *testing.T) {
+		assert.False(t, Not(yes).Match())
+		assert.True(t, Not(no).Match())
+		assert.Equal(t, "not (yes or no)", Not(Or(yes, no)).String())
+	})
+}
+
+func TestSelectWhen(t *testing.T) {
+	whenVault := NewMemoryVault()
+	defaultVault := NewMemoryVault()
+
+	vs := NewVaultSelector(
+		SelectWhen(Not(PredicateFunc("always", func() bool { return true })), whenVault),
+		SelectWhen(PredicateFunc("always", func() bool { return true }), defaultVault),
+	)
+
+	selection, err := vs.Selection()
+	require.NoError(t, err)
+	assert.Equal(t, 1, selection.Index)
+	assert.Equal(t, "always", selection.Description)
+	assert.Same(t, defaultVault, selection.Vault)
+}
@@ -0,0 +1,154 @@
+package goblin
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// SelectPathFunc will use a FilesystemVault rooted at the path returned by the provided
+// function if the path is not empty and is a directory on disk. The function is called each
+// time the selectors are run, so it's safe to provide a function that reads a value
+// that isn't available yet when the vault selector is created. The description is
+// used when reporting the selected vault.
+func SelectPathFunc(
+	description string, getPath func() string, opts ...FilesystemVaultOption,
+) SelectOption {
+	var lock sync.Mutex
+	var lastPath string
+	var lastVault *FilesystemVault
+
+	return func(vs *VaultSelector) {
+		vs.AppendNamedSelector(description, func() Vault {
+			rootPath := strings.TrimSpace(getPath())
+			if rootPath == "" {
+				return nil
+			}
+
+			if info, err := os.Stat(rootPath); err != nil || !info.IsDir() {
+				return nil
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			// Reuse the vault for the same path so watchers and the selected vault
+			// stay the same between selections.
+			if lastVault == nil || lastPath != rootPath {
+				lastPath = rootPath
+				lastVault = NewFilesystemVault(rootPath, opts...)
+			}
+
+			return lastVault
+		})
+	}
+}
+
+// SelectEnvPath will use a FilesystemVault rooted at the path stored in the given
+// environment variable if it's not empty and the path is a directory on disk.
+func SelectEnvPath(envName string, opts ...FilesystemVaultOption) SelectOption {
+	return SelectPathFunc(
+		fmt.Sprintf("path from environment variable %s", envName),
+		func() string {
+			return os.Getenv(envName)
+		},
+		opts...,
+	)
+}
+
+// SelectFlag will use a FilesystemVault rooted at the path provided as the value of
+// the named flag in the flag set if it's not empty and the path is a directory on
+// disk. If the flag set is nil, flag.CommandLine is used.
+//
+// The flag is read when the selectors are run, so the vault selector can be created
+// before the flag set is parsed.
+func SelectFlag(fs *flag.FlagSet, flagName string, opts ...FilesystemVaultOption) SelectOption {
+	return SelectPathFunc(
+		fmt.Sprintf("path from flag --%s", flagName),
+		func() string {
+			flagSet := fs
+			if flagSet == nil {
+				flagSet = flag.CommandLine
+			}
+
+			f := flagSet.Lookup(flagName)
+			if f == nil {
+				return ""
+			}
+
+			return f.Value.String()
+		},
+		opts...,
+	)
+}
+
+// SelectConfigFile will use a FilesystemVault rooted at the path stored under the
+// given key in a key/value config file, if the config file and path both exist.
+// Relative paths are relative to the directory containing the config file.
+//
+// The config file contains one "key = value" or "key: value" pair per line. Blank
+// lines and lines starting with # or ; are ignored, and values may be quoted.
+func SelectConfigFile(configPath string, key string, opts ...FilesystemVaultOption) SelectOption {
+	return SelectPathFunc(
+		fmt.Sprintf("path from key %s in config file %s", key, configPath),
+		func() string {
+			val, err := readConfigValue(configPath, key)
+			if err != nil || val == "" {
+				return ""
+			}
+
+			if !filepath.IsAbs(val) {
+				val = filepath.Join(filepath.Dir(configPath), val)
+			}
+
+			return val
+		},
+		opts...,
+	)
+}
+
+func readConfigValue(configPath string, key string) (string, error) {
+	f, err := os.Open(configPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	lineNum := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lineNum++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		sepIdx := strings.IndexAny(line, "=:")
+		if sepIdx < 0 {
+			return "", fmt.Errorf("%s:%d: expected key and value", configPath, lineNum)
+		}
+
+		if strings.TrimSpace(line[:sepIdx]) != key {
+			continue
+		}
+
+		val := strings.TrimSpace(line[sepIdx+1:])
+		if len(val) >= 2 &&
+			(val[0] == '"' && val[len(val)-1] == '"' ||
+				val[0] == '\'' && val[len(val)-1] == '\'') {
+			val = val[1 : len(val)-1]
+		}
+
+		return val, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", nil
+}
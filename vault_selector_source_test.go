@@ -0,0 +1,211 @@
+package goblin
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectPathFunc(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	t.Run("empty path", func(t *testing.T) {
+		vs := NewVaultSelector(
+			SelectPathFunc("path", func() string { return "" }),
+		)
+		_, err := vs.GetVault()
+		assert.Error(t, err)
+	})
+
+	t.Run("missing path", func(t *testing.T) {
+		vs := NewVaultSelector(
+			SelectPathFunc("path", func() string { return path.Join(td, "missing") }),
+		)
+		_, err := vs.GetVault()
+		assert.Error(t, err)
+	})
+
+	t.Run("file path", func(t *testing.T) {
+		filePath := path.Join(td, "config.yaml")
+		require.NoError(t, ioutil.WriteFile(filePath, []byte("a: b"), 0644))
+		defer os.Remove(filePath)
+
+		defaultVault := NewMemoryVault()
+		vs := NewVaultSelector(
+			SelectPathFunc("path", func() string { return filePath }),
+			SelectDefault(defaultVault),
+		)
+		v, err := vs.GetVault()
+		require.NoError(t, err)
+		assert.Same(t, defaultVault, v)
+	})
+
+	t.Run("existing path", func(t *testing.T) {
+		vs := NewVaultSelector(
+			SelectPathFunc("path", func() string { return td }),
+		)
+		v, err := vs.GetVault()
+		require.NoError(t, err)
+		assert.Equal(t, "Filesystem Vault ("+td+")", v.String())
+	})
+
+	t.Run("vault is reused for the same path", func(t *testing.T) {
+		vs := NewVaultSelector(
+			SelectPathFunc("path", func() string { return td }),
+			SelectReselectInterval(0),
+		)
+		v1, err := vs.GetVault()
+		require.NoError(t, err)
+		v2, err := vs.GetVault()
+		require.NoError(t, err)
+		assert.Same(t, v1, v2)
+	})
+}
+
+func TestSelectFlag(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	t.Run("flag provided", func(t *testing.T) {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.String("assets-dir", "", "")
+
+		vs := NewVaultSelector(
+			SelectFlag(fs, "assets-dir"),
+			SelectDefault(NewMemoryVault()),
+		)
+
+		require.NoError(t, fs.Parse([]string{"--assets-dir=" + td}))
+
+		selection, err := vs.Selection()
+		require.NoError(t, err)
+		assert.Equal(t, 0, selection.Index)
+		assert.Equal(t, "path from flag --assets-dir", selection.Description)
+		assert.Equal(t, "Filesystem Vault ("+td+")", selection.Vault.String())
+	})
+
+	t.Run("flag not provided", func(t *testing.T) {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.String("assets-dir", "", "")
+		require.NoError(t, fs.Parse(nil))
+
+		vs := NewVaultSelector(
+			SelectFlag(fs, "assets-dir"),
+			SelectDefault(NewMemoryVault()),
+		)
+		assert.Equal(t, "Vault Selector (Memory Vault)", vs.String())
+	})
+
+	t.Run("flag not defined", func(t *testing.T) {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		vs := NewVaultSelector(
+			SelectFlag(fs, "assets-dir"),
+			SelectDefault(NewMemoryVault()),
+		)
+		assert.Equal(t, "Vault Selector (Memory Vault)", vs.String())
+	})
+}
+
+func TestSelectEnvPath(t *testing.T) {
+	const envKey = "GOBLIN_TEST_ENV_PATH"
+	defer os.Unsetenv(envKey)
+
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	os.Setenv(envKey, td)
+	vs := NewVaultSelector(
+		SelectEnvPath(envKey),
+		SelectDefault(NewMemoryVault()),
+	)
+	assert.Equal(t, "Vault Selector (Filesystem Vault ("+td+"))", vs.String())
+}
+
+func TestSelectConfigFile(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	require.NoError(t, os.Mkdir(path.Join(td, "web"), 0755))
+
+	configPath := path.Join(td, "app.conf")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(`
+# Application config
+name = app
+assets_dir: "web"
+missing_dir = missing
+`), 0644))
+
+	t.Run("relative path", func(t *testing.T) {
+		vs := NewVaultSelector(
+			SelectConfigFile(configPath, "assets_dir"),
+			SelectDefault(NewMemoryVault()),
+		)
+		assert.Equal(t, "Vault Selector (Filesystem Vault ("+path.Join(td, "web")+"))", vs.String())
+	})
+
+	t.Run("missing path", func(t *testing.T) {
+		vs := NewVaultSelector(
+			SelectConfigFile(configPath, "missing_dir"),
+			SelectDefault(NewMemoryVault()),
+		)
+		assert.Equal(t, "Vault Selector (Memory Vault)", vs.String())
+	})
+
+	t.Run("missing config file", func(t *testing.T) {
+		vs := NewVaultSelector(
+			SelectConfigFile(path.Join(td, "missing.conf"), "assets_dir"),
+			SelectDefault(NewMemoryVault()),
+		)
+		assert.Equal(t, "Vault Selector (Memory Vault)", vs.String())
+	})
+}
+
+func TestReadConfigValue(t *testing.T) {
+	td, err := ioutil.TempDir("", testTempPattern)
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	t.Run("values", func(t *testing.T) {
+		configPath := path.Join(td, "values.conf")
+		require.NoError(t, ioutil.WriteFile(configPath, []byte(`
+; comment
+key1 = value1
+key2: 'value 2'
+  key3=value=3
+`), 0644))
+
+		val, err := readConfigValue(configPath, "key1")
+		require.NoError(t, err)
+		assert.Equal(t, "value1", val)
+
+		val, err = readConfigValue(configPath, "key2")
+		require.NoError(t, err)
+		assert.Equal(t, "value 2", val)
+
+		val, err = readConfigValue(configPath, "key3")
+		require.NoError(t, err)
+		assert.Equal(t, "value=3", val)
+
+		val, err = readConfigValue(configPath, "key4")
+		require.NoError(t, err)
+		assert.Equal(t, "", val)
+	})
+
+	t.Run("invalid line", func(t *testing.T) {
+		configPath := path.Join(td, "invalid.conf")
+		require.NoError(t, ioutil.WriteFile(configPath, []byte("key1 = value1\ninvalid\n"), 0644))
+
+		_, err := readConfigValue(configPath, "key2")
+		assert.EqualError(t, err, configPath+":2: expected key and value")
+	})
+}
@@ -1,9 +1,13 @@
 package goblin
 
 import (
+	"bytes"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestVaultSelectorStringers(t *testing.T) {
@@ -14,3 +18,267 @@ func TestVaultSelectorStringers(t *testing.T) {
 		assert.Equal(t, "Vault Selector (Memory Vault)", vs.String())
 	})
 }
+
+func TestVaultSelectorSelection(t *testing.T) {
+	t.Run("no vault selected", func(t *testing.T) {
+		vs := NewVaultSelector()
+		_, err := vs.Selection()
+		assert.EqualError(t, err, "no vault could be selected")
+	})
+
+	t.Run("winning selector", func(t *testing.T) {
+		const envKey = "GOBLIN_TEST_SELECTION"
+		os.Unsetenv(envKey)
+
+		envVault := NewMemoryVault()
+		defaultVault := NewMemoryVault()
+		vs := NewVaultSelector(
+			SelectEnvBool(envKey, envVault),
+			SelectDefault(defaultVault),
+		)
+
+		selection, err := vs.Selection()
+		require.NoError(t, err)
+		assert.Equal(t, 1, selection.Index)
+		assert.Equal(t, "default", selection.Description)
+		assert.Same(t, defaultVault, selection.Vault)
+	})
+
+	t.Run("custom selector description", func(t *testing.T) {
+		vs := NewVaultSelector()
+		vs.AppendSelector(func() Vault { return nil })
+		vs.AppendSelector(func() Vault { return NewMemoryVault() })
+
+		selection, err := vs.Selection()
+		require.NoError(t, err)
+		assert.Equal(t, 1, selection.Index)
+		assert.Equal(t, "selector 1", selection.Description)
+	})
+}
+
+func TestVaultSelectorReselect(t *testing.T) {
+	t.Run("selection is cached by default", func(t *testing.T) {
+		const envKey = "GOBLIN_TEST_RESELECT_CACHED"
+		os.Unsetenv(envKey)
+		defer os.Unsetenv(envKey)
+
+		envVault := NewMemoryVault()
+		defaultVault := NewMemoryVault()
+		vs := NewVaultSelector(
+			SelectEnvBool(envKey, envVault),
+			SelectDefault(defaultVault),
+		)
+
+		v, err := vs.GetVault()
+		require.NoError(t, err)
+		assert.Same(t, defaultVault, v)
+
+		os.Setenv(envKey, "true")
+		v, err = vs.GetVault()
+		require.NoError(t, err)
+		assert.Same(t, defaultVault, v)
+	})
+
+	t.Run("reselect on demand", func(t *testing.T) {
+		const envKey = "GOBLIN_TEST_RESELECT_DEMAND"
+		os.Unsetenv(envKey)
+		defer os.Unsetenv(envKey)
+
+		envVault := NewMemoryVault()
+		defaultVault := NewMemoryVault()
+
+		var changes []VaultSelection
+		vs := NewVaultSelector(
+			SelectEnvBool(envKey, envVault),
+			SelectDefault(defaultVault),
+			SelectOnChange(func(prev VaultSelection, cur VaultSelection) {
+				changes = append(changes, prev, cur)
+			}),
+		)
+
+		_, err := vs.GetVault()
+		require.NoError(t, err)
+		require.Len(t, changes, 2)
+		assert.Nil(t, changes[0].Vault)
+		assert.Same(t, defaultVault, changes[1].Vault)
+
+		// Selecting the same vault again shouldn't notify
+		_, err = vs.Reselect()
+		require.NoError(t, err)
+		require.Len(t, changes, 2)
+
+		os.Setenv(envKey, "true")
+		v, err := vs.Reselect()
+		require.NoError(t, err)
+		assert.Same(t, envVault, v)
+
+		require.Len(t, changes, 4)
+		assert.Same(t, defaultVault, changes[2].Vault)
+		assert.Same(t, envVault, changes[3].Vault)
+		assert.Equal(t, 0, changes[3].Index)
+	})
+
+	t.Run("reselect on every access", func(t *testing.T) {
+		const envKey = "GOBLIN_TEST_RESELECT_ALWAYS"
+		os.Unsetenv(envKey)
+		defer os.Unsetenv(envKey)
+
+		envVault := NewMemoryVault()
+		defaultVault := NewMemoryVault()
+		vs := NewVaultSelector(
+			SelectEnvBool(envKey, envVault),
+			SelectDefault(defaultVault),
+			SelectReselectInterval(0),
+		)
+
+		v, err := vs.GetVault()
+		require.NoError(t, err)
+		assert.Same(t, defaultVault, v)
+
+		os.Setenv(envKey, "true")
+		v, err = vs.GetVault()
+		require.NoError(t, err)
+		assert.Same(t, envVault, v)
+	})
+
+	t.Run("reselect after interval", func(t *testing.T) {
+		const envKey = "GOBLIN_TEST_RESELECT_INTERVAL"
+		os.Unsetenv(envKey)
+		defer os.Unsetenv(envKey)
+
+		envVault := NewMemoryVault()
+		defaultVault := NewMemoryVault()
+		vs := NewVaultSelector(
+			SelectEnvBool(envKey, envVault),
+			SelectDefault(defaultVault),
+			SelectReselectInterval(time.Hour),
+		)
+
+		v, err := vs.GetVault()
+		require.NoError(t, err)
+		assert.Same(t, defaultVault, v)
+
+		os.Setenv(envKey, "true")
+		v, err = vs.GetVault()
+		require.NoError(t, err)
+		assert.Same(t, defaultVault, v)
+
+		vs.selectedAt = time.Now().Add(-2 * time.Hour)
+		v, err = vs.GetVault()
+		require.NoError(t, err)
+		assert.Same(t, envVault, v)
+	})
+
+	t.Run("failed reselect keeps previous vault", func(t *testing.T) {
+		const envKey = "GOBLIN_TEST_RESELECT_FAILED"
+		os.Setenv(envKey, "true")
+		defer os.Unsetenv(envKey)
+
+		envVault := NewMemoryVault()
+
+		var errs []error
+		vs := NewVaultSelector(
+			SelectEnvBool(envKey, envVault),
+			SelectOnError(func(err error) {
+				errs = append(errs, err)
+			}),
+		)
+
+		v, err := vs.GetVault()
+		require.NoError(t, err)
+		assert.Same(t, envVault, v)
+
+		os.Unsetenv(envKey)
+		_, err = vs.Reselect()
+		assert.EqualError(t, err, "no vault could be selected")
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], "no vault could be selected")
+
+		v, err = vs.GetVault()
+		require.NoError(t, err)
+		assert.Same(t, envVault, v)
+	})
+
+	t.Run("failed expired selection keeps previous vault", func(t *testing.T) {
+		const envKey = "GOBLIN_TEST_RESELECT_EXPIRED"
+		os.Setenv(envKey, "true")
+		defer os.Unsetenv(envKey)
+
+		envVault := NewMemoryVault()
+		vs := NewVaultSelector(
+			SelectEnvBool(envKey, envVault),
+			SelectReselectInterval(0),
+		)
+
+		v, err := vs.GetVault()
+		require.NoError(t, err)
+		assert.Same(t, envVault, v)
+
+		os.Unsetenv(envKey)
+		v, err = vs.GetVault()
+		require.NoError(t, err)
+		assert.Same(t, envVault, v)
+	})
+
+	t.Run("callbacks can use the vault selector", func(t *testing.T) {
+		const envKey = "GOBLIN_TEST_RESELECT_CALLBACK"
+		os.Unsetenv(envKey)
+		defer os.Unsetenv(envKey)
+
+		envVault := NewMemoryVault()
+		require.NoError(t, envVault.WriteFile("file.txt", bytes.NewBufferString("env")))
+		defaultVault := NewMemoryVault()
+		require.NoError(t, defaultVault.WriteFile("file.txt", bytes.NewBufferString("default")))
+
+		var vs *VaultSelector
+		var contents []string
+		vs = NewVaultSelector(
+			SelectEnvBool(envKey, envVault),
+			SelectDefault(defaultVault),
+			SelectReselectInterval(0),
+			SelectOnChange(func(prev VaultSelection, cur VaultSelection) {
+				data, err := vs.ReadFile("file.txt")
+				require.NoError(t, err)
+				contents = append(contents, string(data))
+
+				_, err = vs.Reselect()
+				require.NoError(t, err)
+			}),
+		)
+
+		_, err := vs.GetVault()
+		require.NoError(t, err)
+		os.Setenv(envKey, "true")
+		_, err = vs.GetVault()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"default", "env"}, contents)
+	})
+
+	t.Run("error callbacks can use the vault selector", func(t *testing.T) {
+		const envKey = "GOBLIN_TEST_RESELECT_ERROR_CALLBACK"
+		os.Setenv(envKey, "true")
+		defer os.Unsetenv(envKey)
+
+		envVault := NewMemoryVault()
+
+		var vs *VaultSelector
+		var errVaults []Vault
+		vs = NewVaultSelector(
+			SelectEnvBool(envKey, envVault),
+			SelectOnError(func(err error) {
+				v, err := vs.GetVault()
+				require.NoError(t, err)
+				errVaults = append(errVaults, v)
+			}),
+		)
+
+		_, err := vs.GetVault()
+		require.NoError(t, err)
+
+		os.Unsetenv(envKey)
+		_, err = vs.Reselect()
+		assert.Error(t, err)
+		require.Len(t, errVaults, 1)
+		assert.Same(t, envVault, errVaults[0])
+	})
+}
@@ -0,0 +1,85 @@
+//go:build !windows
+// +build !windows
+
+package goblin
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVaultSelectorReselectOnSignal(t *testing.T) {
+	t.Run("reselect on signal", func(t *testing.T) {
+		const envKey = "GOBLIN_TEST_RESELECT_SIGNAL"
+		os.Unsetenv(envKey)
+		defer os.Unsetenv(envKey)
+
+		envVault := NewMemoryVault()
+		defaultVault := NewMemoryVault()
+
+		changed := make(chan VaultSelection, 2)
+		vs := NewVaultSelector(
+			SelectEnvBool(envKey, envVault),
+			SelectDefault(defaultVault),
+			SelectOnChange(func(prev VaultSelection, cur VaultSelection) {
+				changed <- cur
+			}),
+		)
+		_, err := vs.GetVault()
+		require.NoError(t, err)
+		<-changed
+
+		stop := vs.ReselectOnSignal(syscall.SIGUSR1)
+		defer stop()
+
+		os.Setenv(envKey, "true")
+		require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+
+		select {
+		case cur := <-changed:
+			assert.Same(t, envVault, cur.Vault)
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "vault was not reselected")
+		}
+	})
+
+	t.Run("reselect error on signal", func(t *testing.T) {
+		const envKey = "GOBLIN_TEST_RESELECT_SIGNAL_ERROR"
+		os.Setenv(envKey, "true")
+		defer os.Unsetenv(envKey)
+
+		envVault := NewMemoryVault()
+
+		errs := make(chan error, 1)
+		vs := NewVaultSelector(
+			SelectEnvBool(envKey, envVault),
+			SelectOnError(func(err error) {
+				errs <- err
+			}),
+		)
+		_, err := vs.GetVault()
+		require.NoError(t, err)
+
+		stop := vs.ReselectOnSignal(syscall.SIGUSR2)
+		defer stop()
+
+		os.Unsetenv(envKey)
+		require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR2))
+
+		select {
+		case err := <-errs:
+			assert.EqualError(t, err, "no vault could be selected")
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "reselect error was not reported")
+		}
+
+		v, err := vs.GetVault()
+		require.NoError(t, err)
+		assert.Same(t, envVault, v)
+	})
+}
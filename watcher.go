@@ -0,0 +1,203 @@
+package goblin
+
+import (
+	"path"
+	"strings"
+	"sync"
+)
+
+// WatchEventType is the type of change a WatchEvent describes.
+type WatchEventType int
+
+const (
+	// WatchEventCreate is sent when a file is created.
+	WatchEventCreate WatchEventType = iota + 1
+	// WatchEventModify is sent when the contents or modified time of a file change.
+	WatchEventModify
+	// WatchEventRemove is sent when a file is removed.
+	WatchEventRemove
+)
+
+func (t WatchEventType) String() string {
+	switch t {
+	case WatchEventCreate:
+		return "create"
+	case WatchEventModify:
+		return "modify"
+	case WatchEventRemove:
+		return "remove"
+	default:
+		return "unknown"
+	}
+}
+
+// WatchEvent is a change to a file in a watched vault.
+type WatchEvent struct {
+	Type WatchEventType
+	// Path is the vault-relative path of the file that changed.
+	Path string
+}
+
+// WatchVault is a vault that can notify consumers when files in the vault change.
+type WatchVault interface {
+	Vault
+
+	// Watch returns a Watcher that receives events for all files in the vault with a
+	// path matching the provided pattern. Patterns use the syntax of path.Match and
+	// are matched against the full vault-relative path. The root path "." matches
+	// every file in the vault.
+	Watch(pattern string) (*Watcher, error)
+}
+
+// Watcher receives change events from a vault. Events are queued internally so a
+// slow consumer never blocks the vault, and are delivered on the channel returned
+// by Events in the order they occurred.
+type Watcher struct {
+	pattern string
+	events  chan WatchEvent
+	notify  chan struct{}
+	done    chan struct{}
+	onClose func(*Watcher)
+
+	lock      sync.Mutex
+	queue     []WatchEvent
+	closeOnce sync.Once
+}
+
+func newWatcher(pattern string, onClose func(*Watcher)) (*Watcher, error) {
+	if strings.TrimSpace(pattern) == filesystemRootPath {
+		pattern = filesystemRootPath
+	} else if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		pattern: pattern,
+		events:  make(chan WatchEvent),
+		notify:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		onClose: onClose,
+	}
+
+	go w.run()
+
+	return w, nil
+}
+
+// Events returns the channel events are delivered on. The channel is closed
+// when the watcher is closed.
+func (w *Watcher) Events() <-chan WatchEvent {
+	return w.events
+}
+
+// Close stops the watcher and closes its events channel. Any events that have
+// not been received yet are discarded.
+func (w *Watcher) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.done)
+		if w.onClose != nil {
+			w.onClose(w)
+		}
+	})
+
+	return nil
+}
+
+func (w *Watcher) matches(name string) bool {
+	if w.pattern == filesystemRootPath {
+		return true
+	}
+
+	match, err := path.Match(w.pattern, name)
+	if err != nil {
+		return false
+	}
+
+	return match
+}
+
+func (w *Watcher) send(evt WatchEvent) {
+	if !w.matches(evt.Path) {
+		return
+	}
+
+	w.lock.Lock()
+	w.queue = append(w.queue, evt)
+	w.lock.Unlock()
+
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (w *Watcher) run() {
+	defer close(w.events)
+
+	for {
+		w.lock.Lock()
+		queue := w.queue
+		w.queue = nil
+		w.lock.Unlock()
+
+		for _, evt := range queue {
+			select {
+			case w.events <- evt:
+			case <-w.done:
+				return
+			}
+		}
+
+		select {
+		case <-w.notify:
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// watcherSet is a group of watchers interested in changes to the same vault.
+type watcherSet struct {
+	lock     sync.Mutex
+	watchers map[*Watcher]struct{}
+}
+
+func (ws *watcherSet) Add(pattern string) (*Watcher, error) {
+	w, err := newWatcher(pattern, ws.remove)
+	if err != nil {
+		return nil, err
+	}
+
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+
+	if ws.watchers == nil {
+		ws.watchers = map[*Watcher]struct{}{}
+	}
+	ws.watchers[w] = struct{}{}
+
+	return w, nil
+}
+
+func (ws *watcherSet) remove(w *Watcher) {
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+
+	delete(ws.watchers, w)
+}
+
+func (ws *watcherSet) Len() int {
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+
+	return len(ws.watchers)
+}
+
+func (ws *watcherSet) Send(evt WatchEvent) {
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+
+	for w := range ws.watchers {
+		w.send(evt)
+	}
+}
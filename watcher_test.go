@@ -0,0 +1,83 @@
+package goblin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testWatchTimeout = 5 * time.Second
+
+func receiveWatchEvent(t *testing.T, w *Watcher) WatchEvent {
+	t.Helper()
+
+	select {
+	case evt, ok := <-w.Events():
+		require.True(t, ok, "watcher events channel closed")
+		return evt
+	case <-time.After(testWatchTimeout):
+		require.FailNow(t, "timed out waiting for watch event")
+	}
+
+	return WatchEvent{}
+}
+
+func TestWatcher(t *testing.T) {
+	t.Run("invalid pattern", func(t *testing.T) {
+		w, err := newWatcher("[", nil)
+		assert.Error(t, err)
+		assert.Nil(t, w)
+	})
+
+	t.Run("root pattern matches everything", func(t *testing.T) {
+		w, err := newWatcher(".", nil)
+		require.NoError(t, err)
+		defer w.Close()
+
+		assert.True(t, w.matches("file.txt"))
+		assert.True(t, w.matches("dir1/dir2/file.txt"))
+	})
+
+	t.Run("pattern matches full path", func(t *testing.T) {
+		w, err := newWatcher("dir1/*.html", nil)
+		require.NoError(t, err)
+		defer w.Close()
+
+		assert.True(t, w.matches("dir1/index.html"))
+		assert.False(t, w.matches("index.html"))
+		assert.False(t, w.matches("dir1/index.txt"))
+	})
+
+	t.Run("events are queued until received", func(t *testing.T) {
+		w, err := newWatcher(".", nil)
+		require.NoError(t, err)
+		defer w.Close()
+
+		w.send(WatchEvent{Type: WatchEventCreate, Path: "file1.txt"})
+		w.send(WatchEvent{Type: WatchEventModify, Path: "file1.txt"})
+		w.send(WatchEvent{Type: WatchEventRemove, Path: "file1.txt"})
+
+		assert.Equal(t, WatchEvent{Type: WatchEventCreate, Path: "file1.txt"}, receiveWatchEvent(t, w))
+		assert.Equal(t, WatchEvent{Type: WatchEventModify, Path: "file1.txt"}, receiveWatchEvent(t, w))
+		assert.Equal(t, WatchEvent{Type: WatchEventRemove, Path: "file1.txt"}, receiveWatchEvent(t, w))
+	})
+
+	t.Run("close closes events channel", func(t *testing.T) {
+		closed := false
+		w, err := newWatcher(".", func(*Watcher) { closed = true })
+		require.NoError(t, err)
+
+		require.NoError(t, w.Close())
+		require.NoError(t, w.Close())
+		assert.True(t, closed)
+
+		select {
+		case _, ok := <-w.Events():
+			assert.False(t, ok)
+		case <-time.After(testWatchTimeout):
+			assert.Fail(t, "events channel was not closed")
+		}
+	})
+}